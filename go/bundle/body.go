@@ -0,0 +1,106 @@
+package bundle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// BodySource is a response body of known length whose contents are read only
+// when the bundle is written. Bundle.WriteTo computes the layout of the
+// bundle from Len() and then streams each body from Open() straight to the
+// output, so bodies never need to be held in memory.
+type BodySource interface {
+	// Len returns the length of the body in bytes.
+	Len() int64
+	// Open returns a reader of the body. The reader must yield at least
+	// Len() bytes.
+	Open() (io.ReadCloser, error)
+}
+
+// BodyBytes returns the response body, reading it from BodySource if it is
+// set. A ReaderBody can be read only once, so after BodyBytes it can't be
+// written to a bundle anymore.
+func (r *Response) BodyBytes() ([]byte, error) {
+	if r.BodySource == nil {
+		return r.Body, nil
+	}
+	var buf bytes.Buffer
+	if err := copyBody(&buf, r.BodySource); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type bytesBody []byte
+
+// BytesBody returns a BodySource for an in-memory body.
+func BytesBody(bs []byte) BodySource {
+	return bytesBody(bs)
+}
+
+func (b bytesBody) Len() int64 { return int64(len(b)) }
+func (b bytesBody) Open() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+type fileBody struct {
+	path string
+	size int64
+}
+
+// FileBody returns a BodySource for the contents of the file at path. The
+// file size is recorded at this point; writing the bundle fails if the size
+// has changed since then.
+func FileBody(path string) (BodySource, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, fmt.Errorf("bundle: %q is not a regular file", path)
+	}
+	return &fileBody{path: path, size: fi.Size()}, nil
+}
+
+func (b *fileBody) Len() int64 { return b.size }
+func (b *fileBody) Open() (io.ReadCloser, error) {
+	f, err := os.Open(b.path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if fi.Size() != b.size {
+		f.Close()
+		return nil, fmt.Errorf("bundle: size of %q changed from %d to %d bytes", b.path, b.size, fi.Size())
+	}
+	return f, nil
+}
+
+type readerBody struct {
+	r      io.Reader
+	length int64
+	opened bool
+}
+
+// ReaderBody returns a BodySource that reads length bytes from r. Since r is
+// consumed, a bundle containing it can be written only once.
+func ReaderBody(r io.Reader, length int64) BodySource {
+	return &readerBody{r: r, length: length}
+}
+
+func (b *readerBody) Len() int64 { return b.length }
+func (b *readerBody) Open() (io.ReadCloser, error) {
+	if b.opened {
+		return nil, errors.New("bundle: ReaderBody can be read only once")
+	}
+	b.opened = true
+	return ioutil.NopCloser(b.r), nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/WICG/webpackage/go/bundle/version"
 	"github.com/WICG/webpackage/go/signedexchange/certurl"
@@ -20,6 +21,9 @@ type Response struct {
 	Status int
 	http.Header
 	Body []byte
+	// BodySource, if non-nil, is used instead of Body when writing the
+	// bundle. It allows bodies to be streamed from files or readers.
+	BodySource BodySource
 }
 
 func (r Response) String() string {
@@ -42,12 +46,74 @@ type VouchedSubset struct {
 	Signed    []byte
 }
 
+// LoadType specifies when a browser should load a dependent bundle.
+type LoadType string
+
+const (
+	LoadTypePreload LoadType = "preload"
+	LoadTypeLazy    LoadType = "lazy"
+)
+
+// Dependency declares that the resource at ResourceURL should be loaded from
+// another bundle at BundleURL. [1]
+//
+// [1] https://github.com/WICG/webpackage/blob/main/extensions/proposals/dependencies-section.md
+type Dependency struct {
+	ResourceURL *url.URL
+	BundleURL   *url.URL
+	LoadType    LoadType
+}
+
+// Validate checks the constraints of the dependencies section: both URLs must
+// be absolute or both relative, and BundleURL must be in the same origin and
+// the same or a parent directory as ResourceURL.
+func (d *Dependency) Validate() error {
+	if d.LoadType != LoadTypePreload && d.LoadType != LoadTypeLazy {
+		return fmt.Errorf("bundle: unknown load type %q for dependency %v", d.LoadType, d.ResourceURL)
+	}
+	if d.ResourceURL.IsAbs() != d.BundleURL.IsAbs() {
+		return fmt.Errorf("bundle: dependency %v and its bundle URL %v must be both absolute or both relative", d.ResourceURL, d.BundleURL)
+	}
+	base := &url.URL{Scheme: "https", Host: "base.invalid", Path: "/"}
+	resourceURL := base.ResolveReference(d.ResourceURL)
+	bundleURL := base.ResolveReference(d.BundleURL)
+	if resourceURL.Scheme != bundleURL.Scheme || resourceURL.Host != bundleURL.Host {
+		return fmt.Errorf("bundle: dependency %v and its bundle URL %v must be same-origin", d.ResourceURL, d.BundleURL)
+	}
+	bundleDir := bundleURL.Path[:strings.LastIndex(bundleURL.Path, "/")+1]
+	if !strings.HasPrefix(resourceURL.Path, bundleDir) {
+		return fmt.Errorf("bundle: dependency %v is not under the directory of its bundle URL %v", d.ResourceURL, d.BundleURL)
+	}
+	return nil
+}
+
+// Section is a bundle section that this package does not interpret. Its
+// contents are kept as raw CBOR, so that reading and writing a bundle
+// preserves sections defined by newer extensions.
+type Section struct {
+	Name     string
+	Contents []byte
+	// Before is the name of the known section that followed this section in
+	// the bundle it was read from, so that writing the bundle keeps the
+	// section in place. If empty, or if no such section is written, the
+	// section is written right before the responses section.
+	Before string
+}
+
 type Bundle struct {
 	Version     version.Version
 	PrimaryURL  *url.URL
 	Exchanges   []*Exchange
 	ManifestURL *url.URL
 	Signatures  *Signatures
+	// Dependencies lists the resources to be loaded from other bundles.
+	Dependencies []*Dependency
+	// Critical lists the names of the sections that a decoder must
+	// understand in order to process the bundle.
+	Critical []string
+	// UnknownSections holds the sections not recognized by this package, in
+	// the order they appear in the bundle.
+	UnknownSections []*Section
 }
 
 // AddPayloadIntegrity encodes the exchange's payload with Merkle Integrity
@@ -60,8 +126,14 @@ func (e *Exchange) AddPayloadIntegrity(ver version.Version, recordSize int) (str
 	if e.Response.Header.Get("Digest") != "" {
 		return "", errors.New("bundle: the exchange already has the Digest: header")
 	}
+	if e.Response.BodySource != nil {
+		return "", errors.New("bundle: cannot add payload integrity to a streamed body")
+	}
 
-	encoding := ver.MiceEncoding()
+	encoding, err := ver.MiceEncoding()
+	if err != nil {
+		return "", err
+	}
 	var buf bytes.Buffer
 	digest, err := encoding.Encode(&buf, e.Response.Body, recordSize)
 	if err != nil {
@@ -73,7 +145,8 @@ func (e *Exchange) AddPayloadIntegrity(ver version.Version, recordSize int) (str
 	return encoding.IntegrityIdentifier(), nil
 }
 
-// Validate performs basic sanity checks on the bundle.
+// Validate performs basic sanity checks on the bundle. See Lint for a more
+// thorough check.
 func (b *Bundle) Validate() error {
 	if b.PrimaryURL != nil {
 		hasExchangeForPrimaryURL := false
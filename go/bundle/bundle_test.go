@@ -2,8 +2,12 @@ package bundle_test
 
 import (
 	"bytes"
+	"errors"
+	"io"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -159,3 +163,326 @@ func TestB2BundleWithSpecifiedManifestURLShouldFail(t *testing.T) {
 		t.Errorf("Bundle write should fail as version B2 does not support manifest URL.")
 	}
 }
+
+// countingReaderAt records the total number of bytes read through it.
+type countingReaderAt struct {
+	r    *bytes.Reader
+	read int
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.read += n
+	return n, err
+}
+
+func TestOpenAndFindExchange(t *testing.T) {
+	for _, ver := range version.AllVersions {
+		bundle := createTestBundle(t, ver)
+		bigBody := bytes.Repeat([]byte("x"), 1<<20)
+		bundle.Exchanges = append(bundle.Exchanges, &Exchange{
+			Request{URL: urlMustParse("https://bundle.example.com/big")},
+			Response{
+				Status: 200,
+				Header: http.Header{"Content-Type": []string{"application/octet-stream"}},
+				Body:   bigBody,
+			},
+		})
+
+		var buf bytes.Buffer
+		if _, err := bundle.WriteTo(&buf); err != nil {
+			t.Fatalf("Bundle.WriteTo unexpectedly failed: %v", err)
+		}
+
+		ra := &countingReaderAt{r: bytes.NewReader(buf.Bytes())}
+		br, err := Open(ra, int64(buf.Len()))
+		if err != nil {
+			t.Fatalf("Open unexpectedly failed: %v", err)
+		}
+		if ra.read >= len(bigBody) {
+			t.Errorf("Open read %d bytes; responses should not be read", ra.read)
+		}
+		if br.NumExchanges() != 2 {
+			t.Errorf("NumExchanges: got %d, want 2", br.NumExchanges())
+		}
+		if !reflect.DeepEqual(br.PrimaryURL(), bundle.PrimaryURL) {
+			t.Errorf("PrimaryURL: got %v, want %v", br.PrimaryURL(), bundle.PrimaryURL)
+		}
+
+		before := ra.read
+		e, err := br.FindExchange(urlMustParse("https://bundle.example.com/"))
+		if err != nil {
+			t.Fatalf("FindExchange unexpectedly failed: %v", err)
+		}
+		if !reflect.DeepEqual(e, bundle.Exchanges[0]) {
+			t.Errorf("got: %v\nwant: %v", e, bundle.Exchanges[0])
+		}
+		if ra.read-before >= len(bigBody) {
+			t.Errorf("FindExchange read %d bytes; only the requested response should be read", ra.read-before)
+		}
+
+		if _, err := br.FindExchange(urlMustParse("https://bundle.example.com/missing")); err != ErrExchangeNotFound {
+			t.Errorf("FindExchange for missing URL: got %v, want ErrExchangeNotFound", err)
+		}
+	}
+}
+
+// writerOnly hides any io.ReaderFrom implementation of the wrapped writer.
+type writerOnly struct {
+	io.Writer
+}
+
+func TestWriteWithBodySource(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "file.txt")
+	fileContents := []byte("contents of a file")
+	if err := os.WriteFile(filePath, fileContents, 0644); err != nil {
+		t.Fatal(err)
+	}
+	fileBody, err := FileBody(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readerContents := []byte("contents of a reader")
+
+	bundle := &Bundle{
+		Version:    version.VersionB2,
+		PrimaryURL: urlMustParse("https://bundle.example.com/file"),
+		Exchanges: []*Exchange{
+			&Exchange{
+				Request{URL: urlMustParse("https://bundle.example.com/file")},
+				Response{
+					Status:     200,
+					Header:     http.Header{"Content-Type": []string{"text/plain"}},
+					BodySource: fileBody,
+				},
+			},
+			&Exchange{
+				Request{URL: urlMustParse("https://bundle.example.com/reader")},
+				Response{
+					Status:     200,
+					Header:     http.Header{"Content-Type": []string{"text/plain"}},
+					BodySource: ReaderBody(bytes.NewReader(readerContents), int64(len(readerContents))),
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	n, err := bundle.WriteTo(writerOnly{&buf})
+	if err != nil {
+		t.Fatalf("Bundle.WriteTo unexpectedly failed: %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("Bundle.WriteTo returned %d, but wrote %d bytes", n, buf.Len())
+	}
+
+	deserialized, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Bundle.Read unexpectedly failed: %v", err)
+	}
+	for i, want := range [][]byte{fileContents, readerContents} {
+		if got := deserialized.Exchanges[i].Response.Body; !bytes.Equal(got, want) {
+			t.Errorf("Exchanges[%d] body: got %q, want %q", i, got, want)
+		}
+	}
+
+	// A ReaderBody is consumed by the first write.
+	if _, err := bundle.WriteTo(&buf); err == nil {
+		t.Error("Bundle.WriteTo with a consumed ReaderBody unexpectedly succeeded")
+	}
+}
+
+func TestWriteAndReadUnknownSections(t *testing.T) {
+	for _, ver := range version.AllVersions {
+		bundle := createTestBundle(t, ver)
+		bundle.Critical = []string{"index"}
+		bundle.UnknownSections = []*Section{
+			&Section{Name: "x-foo", Contents: []byte{0x83, 0x01, 0x02, 0x03}}, // [1, 2, 3]
+			&Section{Name: "x-bar", Contents: []byte{0x60}},                   // ""
+		}
+
+		var buf bytes.Buffer
+		if _, err := bundle.WriteTo(&buf); err != nil {
+			t.Fatalf("Bundle.WriteTo unexpectedly failed: %v", err)
+		}
+		deserialized, err := Read(&buf)
+		if err != nil {
+			t.Fatalf("Bundle.Read unexpectedly failed: %v", err)
+		}
+		if !reflect.DeepEqual(deserialized, bundle) {
+			t.Errorf("got: %v\nwant: %v", deserialized, bundle)
+		}
+	}
+}
+
+func TestUnknownSectionsKeepTheirPositions(t *testing.T) {
+	bundle := createTestBundle(t, version.VersionB2)
+	bundle.Critical = []string{"index"}
+	bundle.UnknownSections = []*Section{
+		&Section{Name: "x-first", Contents: []byte{0x60}, Before: "index"},
+		&Section{Name: "x-second", Contents: []byte{0x60}, Before: "index"},
+		&Section{Name: "x-middle", Contents: []byte{0x60}, Before: "critical"},
+		&Section{Name: "x-last", Contents: []byte{0x60}},
+		// There is no manifest section, so this goes before responses.
+		&Section{Name: "x-orphan", Contents: []byte{0x60}, Before: "manifest"},
+	}
+
+	var buf bytes.Buffer
+	if _, err := bundle.WriteTo(&buf); err != nil {
+		t.Fatalf("Bundle.WriteTo unexpectedly failed: %v", err)
+	}
+	encoded := buf.Bytes()
+	// The read sections are before the same known sections as written.
+	deserialized, err := Read(bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatalf("Bundle.Read unexpectedly failed: %v", err)
+	}
+	want := []*Section{
+		&Section{Name: "x-first", Contents: []byte{0x60}, Before: "index"},
+		&Section{Name: "x-second", Contents: []byte{0x60}, Before: "index"},
+		&Section{Name: "x-middle", Contents: []byte{0x60}, Before: "critical"},
+		&Section{Name: "x-last", Contents: []byte{0x60}},
+		&Section{Name: "x-orphan", Contents: []byte{0x60}},
+	}
+	if !reflect.DeepEqual(deserialized.UnknownSections, want) {
+		t.Errorf("got: %v\nwant: %v", deserialized.UnknownSections, want)
+	}
+
+	// Writing the read bundle again gives the same bytes.
+	var buf2 bytes.Buffer
+	if _, err := deserialized.WriteTo(&buf2); err != nil {
+		t.Fatalf("Bundle.WriteTo unexpectedly failed: %v", err)
+	}
+	if !bytes.Equal(buf2.Bytes(), encoded) {
+		t.Error("re-encoding the bundle changed it")
+	}
+}
+
+func TestReadUnknownCriticalSection(t *testing.T) {
+	bundle := createTestBundle(t, version.VersionB2)
+	bundle.Critical = []string{"x-foo"}
+	bundle.UnknownSections = []*Section{&Section{Name: "x-foo", Contents: []byte{0x60}}}
+
+	var buf bytes.Buffer
+	if _, err := bundle.WriteTo(&buf); err != nil {
+		t.Fatalf("Bundle.WriteTo unexpectedly failed: %v", err)
+	}
+	if _, err := Read(&buf); err == nil {
+		t.Error("Bundle.Read unexpectedly succeeded with an unknown critical section")
+	}
+}
+
+func TestReadUnknownVersion(t *testing.T) {
+	for _, ver := range version.AllVersions {
+		bundle := createTestBundle(t, ver)
+		var buf bytes.Buffer
+		if _, err := bundle.WriteTo(&buf); err != nil {
+			t.Fatalf("Bundle.WriteTo unexpectedly failed: %v", err)
+		}
+		// Replace "b1" or "b2" in the version magic with "b9".
+		bs := buf.Bytes()
+		bs[len(version.HeaderMagicBytesB1)+2] = '9'
+
+		_, err := Read(bytes.NewReader(bs))
+		lmerr, ok := err.(*LoadMetadataError)
+		if !ok {
+			t.Fatalf("%s: got error %v, want a LoadMetadataError", ver, err)
+		}
+		if lmerr.Type != VersionError {
+			t.Errorf("%s: Type: got %v, want VersionError", ver, lmerr.Type)
+		}
+		if !errors.Is(err, version.ErrUnknownVersion) {
+			t.Errorf("%s: got error %v, want one wrapping ErrUnknownVersion", ver, err)
+		}
+		if lmerr.FallbackURL == nil || lmerr.FallbackURL.String() != bundle.PrimaryURL.String() {
+			t.Errorf("%s: FallbackURL: got %v, want %v", ver, lmerr.FallbackURL, bundle.PrimaryURL)
+		}
+	}
+}
+
+func TestReadWithOptions(t *testing.T) {
+	bundle := createTestBundle(t, version.VersionB2)
+	var buf bytes.Buffer
+	if _, err := bundle.WriteTo(&buf); err != nil {
+		t.Fatalf("Bundle.WriteTo unexpectedly failed: %v", err)
+	}
+	bs := buf.Bytes()
+
+	if _, err := ReadWithOptions(bytes.NewReader(bs), ReadOptions{
+		MaxSize:        int64(len(bs)),
+		MaxExchanges:   1,
+		MaxHeaders:     1,
+		MaxHeaderBytes: 100,
+		MaxBodySize:    int64(len("hello, world!")),
+	}); err != nil {
+		t.Errorf("ReadWithOptions unexpectedly failed: %v", err)
+	}
+
+	for _, opts := range []ReadOptions{
+		{MaxSize: int64(len(bs)) - 1},
+		{MaxBodySize: 1},
+		{MaxHeaderBytes: 5},
+		{MaxHeaderBytes: 100, MaxBodySize: 1},
+	} {
+		if _, err := ReadWithOptions(bytes.NewReader(bs), opts); err == nil {
+			t.Errorf("ReadWithOptions with %+v unexpectedly succeeded", opts)
+		}
+	}
+
+	other := *bundle.Exchanges[0]
+	other.Request.URL = urlMustParse("https://bundle.example.com/other")
+	bundle.Exchanges = append(bundle.Exchanges, &other)
+	buf.Reset()
+	if _, err := bundle.WriteTo(&buf); err != nil {
+		t.Fatalf("Bundle.WriteTo unexpectedly failed: %v", err)
+	}
+	if _, err := ReadWithOptions(&buf, ReadOptions{MaxExchanges: 1}); err == nil {
+		t.Error("ReadWithOptions with too many exchanges unexpectedly succeeded")
+	}
+}
+
+func TestWriteAndReadDependencies(t *testing.T) {
+	for _, ver := range version.AllVersions {
+		bundle := createTestBundle(t, ver)
+		// Listed in the canonical CBOR order of the resource URLs, which is
+		// the order the decoder returns.
+		bundle.Dependencies = []*Dependency{
+			&Dependency{urlMustParse("a.js"), urlMustParse("a.wbn"), LoadTypePreload},
+			&Dependency{urlMustParse("dir/b.js"), urlMustParse("b.wbn"), LoadTypeLazy},
+			&Dependency{urlMustParse("https://cdn.example.com/util.js"), urlMustParse("https://cdn.example.com/util.wbn"), LoadTypePreload},
+		}
+
+		var buf bytes.Buffer
+		if _, err := bundle.WriteTo(&buf); err != nil {
+			t.Fatalf("Bundle.WriteTo unexpectedly failed: %v", err)
+		}
+		deserialized, err := Read(&buf)
+		if err != nil {
+			t.Fatalf("Bundle.Read unexpectedly failed: %v", err)
+		}
+		if !reflect.DeepEqual(deserialized, bundle) {
+			t.Errorf("got: %v\nwant: %v", deserialized, bundle)
+		}
+	}
+}
+
+func TestInvalidDependencies(t *testing.T) {
+	cases := []*Dependency{
+		&Dependency{urlMustParse("a.js"), urlMustParse("a.wbn"), LoadType("eager")},
+		&Dependency{urlMustParse("a.js"), urlMustParse("https://example.com/a.wbn"), LoadTypePreload},
+		&Dependency{urlMustParse("https://example.com/a.js"), urlMustParse("https://cdn.example.com/a.wbn"), LoadTypePreload},
+		&Dependency{urlMustParse("foo.js"), urlMustParse("dir/foo.wbn"), LoadTypePreload},
+	}
+	for _, d := range cases {
+		if err := d.Validate(); err == nil {
+			t.Errorf("Validate(%v) unexpectedly succeeded", d)
+		}
+		bundle := createTestBundle(t, version.VersionB2)
+		bundle.Dependencies = []*Dependency{d}
+		var buf bytes.Buffer
+		if _, err := bundle.WriteTo(&buf); err == nil {
+			t.Errorf("Bundle.WriteTo with dependency %v unexpectedly succeeded", d)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mrichman/hargo"
+
+	"github.com/WICG/webpackage/go/bundle"
+	"github.com/WICG/webpackage/go/bundle/version"
+	"github.com/WICG/webpackage/go/internal/mimetype"
+)
+
+var (
+	flagInput    = flag.String("i", "in.wbn", "Webbundle input file")
+	flagOutput   = flag.String("o", "out.har", "HAR output file")
+	flagDecodeMI = flag.Bool("decodeMI", false, "Decode response bodies with Merkle Integrity content encoding")
+)
+
+// maxMIRecordSize is the largest record size accepted when decoding a Merkle
+// Integrity encoded body.
+const maxMIRecordSize = 16384
+
+// harFile, harLog and harEntry are used instead of hargo's types for
+// writing: hargo.Har has no JSON tag for "log", hargo.Log always writes a
+// "browser", and hargo.Entry names the timings "pageTimings" instead of
+// "timings".
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string        `json:"version"`
+	Creator hargo.Creator `json:"creator"`
+	Entries []*harEntry   `json:"entries"`
+}
+
+type harEntry struct {
+	StartedDateTime string            `json:"startedDateTime"`
+	Time            float32           `json:"time"`
+	Request         hargo.Request     `json:"request"`
+	Response        hargo.Response    `json:"response"`
+	Cache           hargo.Cache       `json:"cache"`
+	Timings         hargo.PageTimings `json:"timings"`
+}
+
+func readBundleFromFile(path string) (*bundle.Bundle, error) {
+	fi, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open input file %q for reading. err: %v", path, err)
+	}
+	defer fi.Close()
+	b, err := bundle.Read(fi)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return b, nil
+}
+
+func creatorVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		return info.Main.Version
+	}
+	return "unknown"
+}
+
+// headerToNVP converts h to name-value pairs, sorted by name.
+func headerToNVP(h http.Header) []hargo.NVP {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	nvps := []hargo.NVP{}
+	for _, name := range names {
+		for _, value := range h[name] {
+			nvps = append(nvps, hargo.NVP{Name: name, Value: value})
+		}
+	}
+	return nvps
+}
+
+// decodeMI returns the body of res decoded from the Merkle Integrity content
+// encoding, and the response headers without that encoding. If res is not
+// encoded, it returns the body and headers as they are.
+func decodeMI(res *bundle.Response, ver version.Version) ([]byte, http.Header, error) {
+	encoding, err := ver.MiceEncoding()
+	if err != nil {
+		return nil, nil, err
+	}
+	var codings []string
+	encoded := false
+	for _, v := range res.Header.Values("Content-Encoding") {
+		for _, c := range strings.Split(v, ",") {
+			c = strings.TrimSpace(c)
+			if strings.EqualFold(c, encoding.ContentEncoding()) {
+				encoded = true
+			} else if c != "" {
+				codings = append(codings, c)
+			}
+		}
+	}
+	if !encoded {
+		return res.Body, res.Header, nil
+	}
+
+	dec, err := encoding.NewDecoder(bytes.NewReader(res.Body), res.Header.Get(encoding.DigestHeaderName()), maxMIRecordSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := ioutil.ReadAll(dec)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := res.Header.Clone()
+	header.Del("Content-Encoding")
+	if len(codings) > 0 {
+		header.Set("Content-Encoding", strings.Join(codings, ", "))
+	}
+	return body, header, nil
+}
+
+func bodyToContent(body []byte, mimeType string) hargo.Content {
+	c := hargo.Content{
+		Size:     len(body),
+		MimeType: mimeType,
+	}
+	if len(body) == 0 {
+		return c
+	}
+	if mimetype.IsText(mimeType) && utf8.Valid(body) {
+		c.Text = string(body)
+	} else {
+		c.Text = base64.StdEncoding.EncodeToString(body)
+		c.Encoding = "base64"
+	}
+	return c
+}
+
+func exchangeToEntry(e *bundle.Exchange, ver version.Version, now time.Time) (*harEntry, error) {
+	body, header := e.Response.Body, e.Response.Header
+	if *flagDecodeMI {
+		var err error
+		body, header, err = decodeMI(&e.Response, ver)
+		if err != nil {
+			return nil, fmt.Errorf("%v: failed to decode the response body: %v", e.Request.URL, err)
+		}
+	}
+
+	started := now
+	if t, err := http.ParseTime(header.Get("Date")); err == nil {
+		started = t
+	}
+	query := []hargo.NVP{}
+	for name, values := range e.Request.URL.Query() {
+		for _, value := range values {
+			query = append(query, hargo.NVP{Name: name, Value: value})
+		}
+	}
+	sort.SliceStable(query, func(i, j int) bool { return query[i].Name < query[j].Name })
+
+	return &harEntry{
+		StartedDateTime: started.UTC().Format(time.RFC3339Nano),
+		Request: hargo.Request{
+			Method:      http.MethodGet,
+			URL:         e.Request.URL.String(),
+			Cookies:     []hargo.Cookie{},
+			Headers:     headerToNVP(e.Request.Header),
+			QueryString: query,
+			HeaderSize:  -1,
+			BodySize:    0,
+		},
+		Response: hargo.Response{
+			Status:      e.Response.Status,
+			StatusText:  http.StatusText(e.Response.Status),
+			Cookies:     []hargo.Cookie{},
+			Headers:     headerToNVP(header),
+			Content:     bodyToContent(body, header.Get("Content-Type")),
+			RedirectURL: header.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    len(e.Response.Body),
+		},
+	}, nil
+}
+
+func run() error {
+	b, err := readBundleFromFile(*flagInput)
+	if err != nil {
+		return err
+	}
+
+	har := &harFile{
+		Log: harLog{
+			Version: "1.2",
+			Creator: hargo.Creator{Name: "bundle-to-har", Version: creatorVersion()},
+			Entries: []*harEntry{},
+		},
+	}
+	now := time.Now()
+	for _, e := range b.Exchanges {
+		entry, err := exchangeToEntry(e, b.Version, now)
+		if err != nil {
+			return err
+		}
+		har.Log.Entries = append(har.Log.Entries, entry)
+	}
+
+	fo, err := os.OpenFile(*flagOutput, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("Failed to open output file %q for writing. err: %v", *flagOutput, err)
+	}
+	enc := json.NewEncoder(fo)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(har); err != nil {
+		fo.Close()
+		return fmt.Errorf("Failed to write HAR. err: %v", err)
+	}
+	return fo.Close()
+}
+
+func main() {
+	flag.Parse()
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
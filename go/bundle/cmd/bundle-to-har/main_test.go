@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/WICG/webpackage/go/bundle"
+	"github.com/WICG/webpackage/go/bundle/version"
+)
+
+func TestRun(t *testing.T) {
+	b := &bundle.Bundle{
+		Version: version.VersionB2,
+		Exchanges: []*bundle.Exchange{
+			{
+				Request: bundle.Request{URL: mustParseURL(t, "https://example.com/?q=1")},
+				Response: bundle.Response{
+					Status: 200,
+					Header: http.Header{"Content-Type": []string{"text/html"}},
+					Body:   []byte("<p>hello</p>"),
+				},
+			},
+			{
+				Request: bundle.Request{URL: mustParseURL(t, "https://example.com/image.png")},
+				Response: bundle.Response{
+					Status: 200,
+					Header: http.Header{"Content-Type": []string{"image/png"}},
+					Body:   []byte{0x89, 'P', 'N', 'G'},
+				},
+			},
+		},
+	}
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.wbn")
+	f, err := os.Create(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.WriteTo(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	*flagInput = input
+	*flagOutput = filepath.Join(dir, "out.har")
+	if err := run(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(*flagOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var har struct {
+		Log struct {
+			Version string
+			Entries []map[string]json.RawMessage
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(data, &har); err != nil {
+		t.Fatal(err)
+	}
+	if har.Log.Version != "1.2" {
+		t.Errorf("version: got %q, want %q", har.Log.Version, "1.2")
+	}
+	if len(har.Log.Entries) != len(b.Exchanges) {
+		t.Fatalf("got %d entries, want %d", len(har.Log.Entries), len(b.Exchanges))
+	}
+
+	wantKeys := []string{"cache", "request", "response", "startedDateTime", "time", "timings"}
+	wantContents := []map[string]interface{}{
+		{"size": 12.0, "mimeType": "text/html", "text": "<p>hello</p>"},
+		{"size": 4.0, "mimeType": "image/png", "text": "iVBORw==", "encoding": "base64"},
+	}
+	for i, entry := range har.Log.Entries {
+		var keys []string
+		for key := range entry {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if !reflect.DeepEqual(keys, wantKeys) {
+			t.Errorf("entries[%d]: got keys %q, want %q", i, keys, wantKeys)
+		}
+
+		var res struct {
+			Content map[string]interface{}
+		}
+		if err := json.Unmarshal(entry["response"], &res); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(res.Content, wantContents[i]) {
+			t.Errorf("entries[%d]: got content %v, want %v", i, res.Content, wantContents[i])
+		}
+	}
+}
+
+func mustParseURL(t *testing.T, rawURL string) *url.URL {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/WICG/webpackage/go/bundle"
+)
+
+const (
+	mergeSubCmdName  = "merge"
+	filterSubCmdName = "filter"
+	splitSubCmdName  = "split"
+)
+
+type stringArgs []string
+
+func (s *stringArgs) String() string {
+	return fmt.Sprintf("%v", *s)
+}
+
+func (s *stringArgs) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+var (
+	mergeCmd             = flag.NewFlagSet(mergeSubCmdName, flag.ExitOnError)
+	mergeFlagOutput      = mergeCmd.String("o", "out.wbn", "Webbundle output file")
+	mergeFlagDuplicates  = mergeCmd.String("duplicates", "error", "How to resolve exchanges for the same URL with different responses: 'error', 'first' or 'last'")
+	mergeFlagPrimaryURL  = mergeCmd.String("primaryURLConflict", "error", "How to resolve different primary URLs: 'error', 'first' or 'last'")
+	mergeFlagManifestURL = mergeCmd.String("manifestURLConflict", "error", "How to resolve different manifest URLs: 'error', 'first' or 'last'")
+)
+
+var (
+	filterCmd        = flag.NewFlagSet(filterSubCmdName, flag.ExitOnError)
+	filterFlagInput  = filterCmd.String("i", "in.wbn", "Webbundle input file")
+	filterFlagOutput = filterCmd.String("o", "out.wbn", "Webbundle output file")
+	filterFlagGlob   = filterCmd.String("glob", "", "Keep exchanges whose URL matches this glob pattern ('*' does not match '/', '**' does)")
+	filterFlagRegexp = filterCmd.String("regexp", "", "Keep exchanges whose URL matches this regular expression")
+)
+
+var (
+	splitCmd         = flag.NewFlagSet(splitSubCmdName, flag.ExitOnError)
+	splitFlagInput   = splitCmd.String("i", "in.wbn", "Webbundle input file")
+	splitFlagOutput  = splitCmd.String("o", "out.wbn", "Webbundle output file name; the N-th bundle is written to out-N.wbn")
+	splitFlagMaxSize = splitCmd.Int64("maxSize", 0, "Maximum size of each output bundle in bytes")
+	splitFlagPrefix  = stringArgs{}
+)
+
+func init() {
+	splitCmd.Var(&splitFlagPrefix, "prefix", "Put exchanges whose URL starts with this prefix into a separate bundle. Can be repeated.")
+}
+
+func parseConflictPolicy(value string) (bundle.ConflictPolicy, error) {
+	switch value {
+	case "error":
+		return bundle.ConflictError, nil
+	case "first":
+		return bundle.ConflictKeepFirst, nil
+	case "last":
+		return bundle.ConflictKeepLast, nil
+	default:
+		return 0, fmt.Errorf("unknown conflict policy %q, expected 'error', 'first' or 'last'", value)
+	}
+}
+
+func readBundleFromFile(path string) (*bundle.Bundle, error) {
+	fi, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fi.Close()
+	return bundle.Read(fi)
+}
+
+func writeBundleToFile(b *bundle.Bundle, path string) error {
+	fo, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer fo.Close()
+	_, err = b.WriteTo(fo)
+	return err
+}
+
+func Merge() error {
+	var opts bundle.MergeOptions
+	var err error
+	if opts.Exchanges, err = parseConflictPolicy(*mergeFlagDuplicates); err != nil {
+		return err
+	}
+	if opts.PrimaryURL, err = parseConflictPolicy(*mergeFlagPrimaryURL); err != nil {
+		return err
+	}
+	if opts.ManifestURL, err = parseConflictPolicy(*mergeFlagManifestURL); err != nil {
+		return err
+	}
+	if mergeCmd.NArg() == 0 {
+		return errors.New("Please specify the input bundle files to merge.")
+	}
+
+	var bs []*bundle.Bundle
+	for _, path := range mergeCmd.Args() {
+		b, err := readBundleFromFile(path)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		bs = append(bs, b)
+	}
+	merged, err := bundle.Merge(opts, bs...)
+	if err != nil {
+		return err
+	}
+	if err := writeBundleToFile(merged, *mergeFlagOutput); err != nil {
+		return fmt.Errorf("%s: %v", *mergeFlagOutput, err)
+	}
+	return nil
+}
+
+func Filter() error {
+	var m bundle.URLMatcher
+	switch {
+	case *filterFlagGlob != "" && *filterFlagRegexp != "":
+		return errors.New("Please specify only one of -glob and -regexp.")
+	case *filterFlagGlob != "":
+		var err error
+		m, err = bundle.GlobMatcher(*filterFlagGlob)
+		if err != nil {
+			return fmt.Errorf("invalid glob pattern %q: %v", *filterFlagGlob, err)
+		}
+	case *filterFlagRegexp != "":
+		re, err := regexp.Compile(*filterFlagRegexp)
+		if err != nil {
+			return fmt.Errorf("invalid regular expression %q: %v", *filterFlagRegexp, err)
+		}
+		m = bundle.RegexpMatcher(re)
+	default:
+		return errors.New("Please specify -glob or -regexp.")
+	}
+
+	b, err := readBundleFromFile(*filterFlagInput)
+	if err != nil {
+		return fmt.Errorf("%s: %v", *filterFlagInput, err)
+	}
+	filtered := bundle.Filter(b, m)
+	if err := writeBundleToFile(filtered, *filterFlagOutput); err != nil {
+		return fmt.Errorf("%s: %v", *filterFlagOutput, err)
+	}
+	return nil
+}
+
+// splitOutputPath returns the path for the i-th bundle, e.g. "out-1.wbn" for
+// "out.wbn".
+func splitOutputPath(path string, i int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), i, ext)
+}
+
+func Split() error {
+	if (*splitFlagMaxSize > 0) == (len(splitFlagPrefix) > 0) {
+		return errors.New("Please specify either -maxSize or -prefix.")
+	}
+
+	b, err := readBundleFromFile(*splitFlagInput)
+	if err != nil {
+		return fmt.Errorf("%s: %v", *splitFlagInput, err)
+	}
+
+	var bs []*bundle.Bundle
+	if *splitFlagMaxSize > 0 {
+		bs, err = bundle.SplitBySize(b, *splitFlagMaxSize)
+		if err != nil {
+			return err
+		}
+	} else {
+		bs = bundle.SplitByPrefix(b, splitFlagPrefix)
+	}
+
+	n := 0
+	for _, sb := range bs {
+		if len(sb.Exchanges) == 0 {
+			continue
+		}
+		path := splitOutputPath(*splitFlagOutput, n)
+		if err := writeBundleToFile(sb, path); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		fmt.Printf("%s: %d exchanges\n", path, len(sb.Exchanges))
+		n++
+	}
+	return nil
+}
+
+func run() error {
+	if len(os.Args) < 2 {
+		return fmt.Errorf("Please specify a subcommand: '%s', '%s' or '%s'", mergeSubCmdName, filterSubCmdName, splitSubCmdName)
+	}
+	switch os.Args[1] {
+
+	case mergeSubCmdName:
+		mergeCmd.Parse(os.Args[2:])
+		return Merge()
+
+	case filterSubCmdName:
+		filterCmd.Parse(os.Args[2:])
+		return Filter()
+
+	case splitSubCmdName:
+		splitCmd.Parse(os.Args[2:])
+		return Split()
+
+	default:
+		return fmt.Errorf("Unknown subcommand, try '%s', '%s' or '%s'", mergeSubCmdName, filterSubCmdName, splitSubCmdName)
+	}
+}
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/WICG/webpackage/go/bundle"
+	"github.com/WICG/webpackage/go/bundle/version"
+)
+
+var (
+	flagInput     = flag.String("i", "in.wbn", "Webbundle input file")
+	flagOutput    = flag.String("o", "out.wbn", "Webbundle output file")
+	flagVersion   = flag.String("to", "", "The webbundle format version to convert to. Possible values are: 'b1' and 'b2'")
+	flagAllowLoss = flag.Bool("allowLoss", false, "Drop the features the target version does not support, instead of failing")
+)
+
+func run() error {
+	ver, ok := version.Parse(*flagVersion)
+	if !ok {
+		return errors.New("Error: unknown version, specify -to b1 or -to b2")
+	}
+
+	fi, err := os.Open(*flagInput)
+	if err != nil {
+		return fmt.Errorf("Failed to open input file %q for reading. err: %v", *flagInput, err)
+	}
+	defer fi.Close()
+	b, err := bundle.Read(fi)
+	if err != nil {
+		return fmt.Errorf("%s: %v", *flagInput, err)
+	}
+
+	converted, lost, err := b.ConvertTo(ver)
+	if err != nil {
+		return err
+	}
+	if len(lost) > 0 {
+		if !*flagAllowLoss {
+			return fmt.Errorf("Converting to %s would drop:\n  %s\nPass -allowLoss to convert anyway.", ver, strings.Join(lost, "\n  "))
+		}
+		for _, l := range lost {
+			log.Printf("Dropped %s", l)
+		}
+	}
+
+	fo, err := os.OpenFile(*flagOutput, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("Failed to open output file %q for writing. err: %v", *flagOutput, err)
+	}
+	defer fo.Close()
+	if _, err := converted.WriteTo(fo); err != nil {
+		return fmt.Errorf("Failed to write bundle. err: %v", err)
+	}
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/WICG/webpackage/go/bundle"
+	"github.com/WICG/webpackage/go/internal/mimetype"
+	"github.com/WICG/webpackage/go/internal/textdiff"
+)
+
+var (
+	flagJSON = flag.Bool("json", false, "Print the differences as JSON")
+	flagBody = flag.Bool("body", true, "Print diffs of text response bodies")
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] old.wbn new.wbn\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+func readBundleFromFile(path string) (*bundle.Bundle, error) {
+	fi, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open input file %q for reading. err: %v", path, err)
+	}
+	defer fi.Close()
+	b, err := bundle.Read(fi)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return b, nil
+}
+
+// jsonBundleDiff, jsonExchangeDiff and jsonBodyChange add the text diffs of the
+// bodies to the JSON output.
+type jsonBundleDiff struct {
+	*bundle.BundleDiff
+	ChangedExchanges []*jsonExchangeDiff `json:",omitempty"`
+}
+
+type jsonExchangeDiff struct {
+	*bundle.ExchangeDiff
+	Body *jsonBodyChange `json:",omitempty"`
+}
+
+type jsonBodyChange struct {
+	*bundle.BodyChange
+	TextDiff string `json:",omitempty"`
+}
+
+// textDiff returns a unified diff of the bodies of e, if both of them are
+// text according to their Content-Type.
+func textDiff(e *bundle.ExchangeDiff) (string, error) {
+	if !mimetype.IsText(e.Old.Response.Header.Get("Content-Type")) || !mimetype.IsText(e.New.Response.Header.Get("Content-Type")) {
+		return "", nil
+	}
+	old, err := e.Old.Response.BodyBytes()
+	if err != nil {
+		return "", err
+	}
+	new, err := e.New.Response.BodyBytes()
+	if err != nil {
+		return "", err
+	}
+	return textdiff.Unified(string(old), string(new)), nil
+}
+
+func printJSON(w io.Writer, d *bundle.BundleDiff) error {
+	jd := &jsonBundleDiff{BundleDiff: d}
+	for _, e := range d.ChangedExchanges {
+		je := &jsonExchangeDiff{ExchangeDiff: e}
+		if e.Body != nil {
+			je.Body = &jsonBodyChange{BodyChange: e.Body}
+			if *flagBody {
+				var err error
+				if je.Body.TextDiff, err = textDiff(e); err != nil {
+					return err
+				}
+			}
+		}
+		jd.ChangedExchanges = append(jd.ChangedExchanges, je)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "   ")
+	return enc.Encode(jd)
+}
+
+func printChange(w io.Writer, name string, c *bundle.Change) {
+	if c == nil {
+		return
+	}
+	old, new := c.Old, c.New
+	if old == "" {
+		old = "(none)"
+	}
+	if new == "" {
+		new = "(none)"
+	}
+	fmt.Fprintf(w, "%s: %s -> %s\n", name, old, new)
+}
+
+func printHeaderValues(vs []string) string {
+	if vs == nil {
+		return "(none)"
+	}
+	return fmt.Sprintf("%q", vs)
+}
+
+func printText(w io.Writer, d *bundle.BundleDiff) error {
+	printChange(w, "Version", d.Version)
+	printChange(w, "Primary URL", d.PrimaryURL)
+	printChange(w, "Manifest URL", d.ManifestURL)
+	for _, a := range d.AddedAuthorities {
+		fmt.Fprintf(w, "Added certificate: %s\n", a)
+	}
+	for _, a := range d.RemovedAuthorities {
+		fmt.Fprintf(w, "Removed certificate: %s\n", a)
+	}
+	for _, u := range d.AddedExchanges {
+		fmt.Fprintf(w, "Added: %s\n", u)
+	}
+	for _, u := range d.RemovedExchanges {
+		fmt.Fprintf(w, "Removed: %s\n", u)
+	}
+	for _, e := range d.ChangedExchanges {
+		fmt.Fprintf(w, "Changed: %s\n", e.URL)
+		printChange(w, "  :status", e.Status)
+		for _, h := range e.Headers {
+			fmt.Fprintf(w, "  %s: %s -> %s\n", h.Name, printHeaderValues(h.Old), printHeaderValues(h.New))
+		}
+		if e.Body == nil {
+			continue
+		}
+		fmt.Fprintf(w, "  [len(Body)]: %d -> %d\n", e.Body.OldLength, e.Body.NewLength)
+		if !*flagBody {
+			continue
+		}
+		diff, err := textDiff(e)
+		if err != nil {
+			return err
+		}
+		if diff != "" {
+			for _, line := range strings.SplitAfter(strings.TrimSuffix(diff, "\n"), "\n") {
+				fmt.Fprintf(w, "    %s", line)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	return nil
+}
+
+func run() error {
+	if flag.NArg() != 2 {
+		usage()
+		return errors.New("Please specify two bundle files to compare.")
+	}
+	a, err := readBundleFromFile(flag.Arg(0))
+	if err != nil {
+		return err
+	}
+	b, err := readBundleFromFile(flag.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	d, err := bundle.Diff(a, b)
+	if err != nil {
+		return err
+	}
+	if *flagJSON {
+		return printJSON(os.Stdout, d)
+	}
+	if d.Empty() {
+		fmt.Println("No differences.")
+		return nil
+	}
+	return printText(os.Stdout, d)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
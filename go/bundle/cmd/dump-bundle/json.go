@@ -0,0 +1,199 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/WICG/webpackage/go/bundle"
+	"github.com/WICG/webpackage/go/bundle/signature"
+	"github.com/WICG/webpackage/go/integrityblock/webbundleid"
+)
+
+type jsonBundle struct {
+	Version         string
+	PrimaryURL      string              `json:",omitempty"`
+	ManifestURL     string              `json:",omitempty"`
+	Dependencies    []*jsonDependency   `json:",omitempty"`
+	Critical        []string            `json:",omitempty"`
+	UnknownSections []*jsonSection      `json:",omitempty"`
+	Signatures      *jsonSignatures     `json:",omitempty"`
+	IntegrityBlock  *jsonIntegrityBlock `json:",omitempty"`
+	Exchanges       []*jsonExchange
+}
+
+type jsonDependency struct {
+	ResourceURL string
+	BundleURL   string
+	LoadType    string
+}
+
+type jsonSection struct {
+	Name string
+	// Contents is base64-encoded.
+	Contents []byte
+}
+
+type jsonSignatures struct {
+	Certificates []*jsonCertificate
+	// Error is the error of verifying the signatures section, if any.
+	Error string `json:",omitempty"`
+}
+
+type jsonCertificate struct {
+	Subject   string
+	Issuer    string
+	NotBefore time.Time
+	NotAfter  time.Time
+	// SHA256 is the hex-encoded SHA-256 hash of the DER-encoded certificate.
+	SHA256 string
+}
+
+type jsonIntegrityBlock struct {
+	// WebBundleID is the Web Bundle ID declared by a version 2 integrity block.
+	WebBundleID string `json:",omitempty"`
+	Signatures  []*jsonIntegritySignature
+}
+
+type jsonIntegritySignature struct {
+	// Attributes holds the base64-encoded signature attributes.
+	Attributes  map[string]string
+	WebBundleID string `json:",omitempty"`
+	Verified    bool
+	Error       string `json:",omitempty"`
+}
+
+type jsonExchange struct {
+	URL            string
+	RequestHeaders http.Header `json:",omitempty"`
+	Status         int
+	Headers        http.Header
+	BodyLength     int
+	// BodySHA256 is the hex-encoded SHA-256 hash of the body.
+	BodySHA256   string
+	Verification *jsonVerification `json:",omitempty"`
+}
+
+// jsonVerification is the result of verifying an exchange against the
+// signatures section. Verified is false and Error is empty if the exchange is
+// not signed.
+type jsonVerification struct {
+	Verified bool
+	// Certificate is the index of the certificate that signed the exchange.
+	Certificate *int   `json:",omitempty"`
+	Error       string `json:",omitempty"`
+}
+
+func newJSONIntegrityBlock(info *integrityBlockInfo) *jsonIntegrityBlock {
+	jib := &jsonIntegrityBlock{WebBundleID: info.integrityBlock.WebBundleId()}
+	for i, is := range info.integrityBlock.SignatureStack {
+		js := &jsonIntegritySignature{Attributes: make(map[string]string)}
+		for name, value := range is.SignatureAttributes {
+			js.Attributes[name] = base64.StdEncoding.EncodeToString(value)
+		}
+		if publicKey, err := is.PublicKey(); err == nil {
+			js.WebBundleID, _ = webbundleid.GetWebBundleId(publicKey)
+		}
+		if err := info.integrityBlock.VerifySignature(i, info.webBundleHash); err != nil {
+			js.Error = err.Error()
+		} else {
+			js.Verified = true
+		}
+		jib.Signatures = append(jib.Signatures, js)
+	}
+	return jib
+}
+
+func newJSONExchange(e *bundle.Exchange, b *bundle.Bundle, verifier *signature.Verifier) (*jsonExchange, error) {
+	body, err := e.Response.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	je := &jsonExchange{
+		URL:            e.Request.URL.String(),
+		RequestHeaders: e.Request.Header,
+		Status:         e.Response.Status,
+		Headers:        e.Response.Header,
+		BodyLength:     len(body),
+		BodySHA256:     hex.EncodeToString(sum[:]),
+	}
+	if verifier != nil {
+		je.Verification = &jsonVerification{}
+		result, cert, err := verifyExchange(e, b, verifier)
+		if err != nil {
+			je.Verification.Error = err.Error()
+		} else if result != nil {
+			je.Verification.Verified = true
+			if cert >= 0 {
+				je.Verification.Certificate = &cert
+			}
+		}
+	}
+	return je, nil
+}
+
+// DumpJSON prints the bundle and the exchanges es as JSON.
+func DumpJSON(w io.Writer, b *bundle.Bundle, ibInfo *integrityBlockInfo, es []*bundle.Exchange) error {
+	jb := &jsonBundle{
+		Version:   string(b.Version),
+		Exchanges: []*jsonExchange{},
+	}
+	if b.PrimaryURL != nil {
+		jb.PrimaryURL = b.PrimaryURL.String()
+	}
+	if b.ManifestURL != nil {
+		jb.ManifestURL = b.ManifestURL.String()
+	}
+	for _, d := range b.Dependencies {
+		jb.Dependencies = append(jb.Dependencies, &jsonDependency{
+			ResourceURL: d.ResourceURL.String(),
+			BundleURL:   d.BundleURL.String(),
+			LoadType:    string(d.LoadType),
+		})
+	}
+	jb.Critical = b.Critical
+	for _, s := range b.UnknownSections {
+		jb.UnknownSections = append(jb.UnknownSections, &jsonSection{Name: s.Name, Contents: s.Contents})
+	}
+	if ibInfo != nil {
+		jb.IntegrityBlock = newJSONIntegrityBlock(ibInfo)
+	}
+
+	var verifier *signature.Verifier
+	if b.Signatures != nil {
+		jb.Signatures = &jsonSignatures{Certificates: []*jsonCertificate{}}
+		for _, ac := range b.Signatures.Authorities {
+			sum := sha256.Sum256(ac.Cert.Raw)
+			jb.Signatures.Certificates = append(jb.Signatures.Certificates, &jsonCertificate{
+				Subject:   ac.Cert.Subject.CommonName,
+				Issuer:    ac.Cert.Issuer.CommonName,
+				NotBefore: ac.Cert.NotBefore,
+				NotAfter:  ac.Cert.NotAfter,
+				SHA256:    hex.EncodeToString(sum[:]),
+			})
+		}
+		var err error
+		verifier, err = signature.NewVerifier(b.Signatures, time.Now(), b.Version)
+		if err != nil {
+			jb.Signatures.Error = err.Error()
+		}
+	}
+
+	for _, e := range es {
+		je, err := newJSONExchange(e, b, verifier)
+		if err != nil {
+			return err
+		}
+		jb.Exchanges = append(jb.Exchanges, je)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "   ")
+	return enc.Encode(jb)
+}
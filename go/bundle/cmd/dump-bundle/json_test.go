@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/WICG/webpackage/go/bundle"
+	"github.com/WICG/webpackage/go/bundle/version"
+)
+
+func mustParseURL(t *testing.T, rawURL string) *url.URL {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestDumpJSON(t *testing.T) {
+	b := &bundle.Bundle{
+		Version:    version.VersionB2,
+		PrimaryURL: mustParseURL(t, "https://example.com/"),
+		Exchanges: []*bundle.Exchange{
+			{
+				Request: bundle.Request{URL: mustParseURL(t, "https://example.com/")},
+				Response: bundle.Response{
+					Status: 200,
+					Header: http.Header{"Content-Type": []string{"text/plain"}},
+					Body:   []byte("hello"),
+				},
+			},
+			{
+				Request: bundle.Request{URL: mustParseURL(t, "https://example.com/streamed")},
+				Response: bundle.Response{
+					Status:     200,
+					Header:     http.Header{},
+					BodySource: bundle.BytesBody([]byte("hello")),
+				},
+			},
+		},
+		Dependencies: []*bundle.Dependency{
+			{
+				ResourceURL: mustParseURL(t, "https://example.com/lib/a.js"),
+				BundleURL:   mustParseURL(t, "https://example.com/lib.wbn"),
+				LoadType:    bundle.LoadTypePreload,
+			},
+		},
+		Critical:        []string{"index", "x-foo"},
+		UnknownSections: []*bundle.Section{{Name: "x-foo", Contents: []byte{0x60}}},
+	}
+
+	var buf bytes.Buffer
+	if err := DumpJSON(&buf, b, nil, b.Exchanges); err != nil {
+		t.Fatal(err)
+	}
+	var got jsonBundle
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := jsonBundle{
+		Version:    "b2",
+		PrimaryURL: "https://example.com/",
+		Dependencies: []*jsonDependency{
+			{ResourceURL: "https://example.com/lib/a.js", BundleURL: "https://example.com/lib.wbn", LoadType: "preload"},
+		},
+		Critical:        []string{"index", "x-foo"},
+		UnknownSections: []*jsonSection{{Name: "x-foo", Contents: []byte{0x60}}},
+		Exchanges: []*jsonExchange{
+			{
+				URL:        "https://example.com/",
+				Status:     200,
+				Headers:    http.Header{"Content-Type": []string{"text/plain"}},
+				BodyLength: 5,
+				BodySHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+			},
+			{
+				URL:        "https://example.com/streamed",
+				Status:     200,
+				Headers:    http.Header{},
+				BodyLength: 5,
+				BodySHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		gotJSON, _ := json.Marshal(got)
+		wantJSON, _ := json.Marshal(want)
+		t.Errorf("got %s\nwant %s", gotJSON, wantJSON)
+	}
+}
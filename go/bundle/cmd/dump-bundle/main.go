@@ -1,57 +1,159 @@
 package main
 
 import (
-	"errors"
+	"encoding/base64"
 	"flag"
 	"fmt"
+	"io"
 	"log"
-	"mime"
 	"os"
-	"strings"
+	"sort"
 	"time"
 
 	"github.com/WICG/webpackage/go/bundle"
 	"github.com/WICG/webpackage/go/bundle/signature"
 	"github.com/WICG/webpackage/go/integrityblock"
+	"github.com/WICG/webpackage/go/integrityblock/webbundleid"
+	"github.com/WICG/webpackage/go/internal/mimetype"
 )
 
 var (
 	flagInput           = flag.String("i", "in.webbundle", "Webbundle input file")
 	flagDumpContentText = flag.Bool("contentText", true, "Dump response content if text")
+	flagJSON            = flag.Bool("json", false, "Print output as JSON")
+	flagURL             = flag.String("url", "", "Dump only the exchanges for this URL")
+	flagURLPattern      = flag.String("urlPattern", "", "Dump only the exchanges whose URL matches this glob pattern ('*' and '?' don't match '/', '**' does)")
+	flagBody            = flag.Bool("body", false, "Write the raw response body of the single exchange selected by -url or -urlPattern to stdout")
 )
 
-func ReadBundleFromFile(path string) (*bundle.Bundle, error) {
+// filterExchanges returns the exchanges selected by -url and -urlPattern.
+func filterExchanges(es []*bundle.Exchange) ([]*bundle.Exchange, error) {
+	var match bundle.URLMatcher
+	if *flagURLPattern != "" {
+		var err error
+		match, err = bundle.GlobMatcher(*flagURLPattern)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid -urlPattern %q: %v", *flagURLPattern, err)
+		}
+	}
+	var filtered []*bundle.Exchange
+	for _, e := range es {
+		if *flagURL != "" && e.Request.URL.String() != *flagURL {
+			continue
+		}
+		if match != nil && !match(e.Request.URL) {
+			continue
+		}
+		filtered = append(filtered, e)
+	}
+	return filtered, nil
+}
+
+// integrityBlockInfo is the integrity block of a signed web bundle, with the
+// SHA-512 hash of the web bundle following it.
+type integrityBlockInfo struct {
+	integrityBlock *integrityblock.IntegrityBlock
+	webBundleHash  []byte
+}
+
+// ReadBundleFromFile reads the web bundle in the file at path. If the web
+// bundle is signed with an integrity block, the integrity block is returned as
+// well.
+func ReadBundleFromFile(path string) (*bundle.Bundle, *integrityBlockInfo, error) {
 	fi, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to open input file %q for reading. err: %v", path, err)
+		return nil, nil, fmt.Errorf("Failed to open input file %q for reading. err: %v", path, err)
 	}
 	defer fi.Close()
 
 	hasIntegrityBlock, err := integrityblock.WebBundleHasIntegrityBlock(fi)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
+	var ibInfo *integrityBlockInfo
+	var r io.Reader = fi
 	if hasIntegrityBlock {
-		return nil, errors.New("dump-bundle doesn't support bundles which have been signed using integrity block.")
+		ib, offset, err := integrityblock.ParseIntegrityBlock(fi)
+		if err != nil {
+			return nil, nil, err
+		}
+		hash, err := integrityblock.ComputeWebBundleSha512(fi, offset)
+		if err != nil {
+			return nil, nil, err
+		}
+		ibInfo = &integrityBlockInfo{integrityBlock: ib, webBundleHash: hash}
+		if _, err := fi.Seek(offset, io.SeekStart); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	b, err := bundle.Read(r)
+	if lmerr, ok := err.(*bundle.LoadMetadataError); ok && lmerr.Type == bundle.VersionError && lmerr.FallbackURL != nil {
+		return nil, nil, fmt.Errorf("%v (fallback URL: %v)", err, lmerr.FallbackURL)
+	}
+	return b, ibInfo, err
+}
+
+// DumpIntegrityBlock prints the signature stack of the integrity block, and
+// whether each signature verifies against the web bundle.
+func DumpIntegrityBlock(info *integrityBlockInfo) {
+	fmt.Println("Integrity block:")
+	if info.integrityBlock.IsVersionB2() {
+		fmt.Println("  Declared Web Bundle ID:", info.integrityBlock.WebBundleId())
+	}
+	for i, is := range info.integrityBlock.SignatureStack {
+		fmt.Printf("  Signature #%d:\n", i)
+		names := make([]string, 0, len(is.SignatureAttributes))
+		for name := range is.SignatureAttributes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("    %s: %s\n", name, base64.StdEncoding.EncodeToString(is.SignatureAttributes[name]))
+		}
+		if publicKey, err := is.PublicKey(); err == nil {
+			if webBundleId, err := webbundleid.GetWebBundleId(publicKey); err == nil {
+				fmt.Println("    Web Bundle ID:", webBundleId)
+			}
+		}
+		if err := info.integrityBlock.VerifySignature(i, info.webBundleHash); err != nil {
+			fmt.Printf("    [Signature verification error: %v]\n", err)
+		} else {
+			fmt.Println("    [Signature verified]")
+		}
 	}
+}
 
-	return bundle.Read(fi)
+// verifyExchange verifies e against the signatures section of b. It returns
+// nil if e is not signed, and the index of the certificate that signed e.
+func verifyExchange(e *bundle.Exchange, b *bundle.Bundle, verifier *signature.Verifier) (*signature.VerifyExchangeResult, int, error) {
+	result, err := verifier.VerifyExchange(e)
+	if err != nil || result == nil {
+		return nil, -1, err
+	}
+	for i, auth := range b.Signatures.Authorities {
+		if result.Authority == auth {
+			return result, i, nil
+		}
+	}
+	return result, -1, nil
 }
 
 func DumpExchange(e *bundle.Exchange, b *bundle.Bundle, verifier *signature.Verifier) error {
-	payload := e.Response.Body
+	body, err := e.Response.BodyBytes()
+	if err != nil {
+		return err
+	}
+	payload := body
 	if verifier != nil {
-		result, err := verifier.VerifyExchange(e)
+		result, cert, err := verifyExchange(e, b, verifier)
 		if err != nil {
 			fmt.Printf("[Response verification error: %v]\n", err)
 		} else if result != nil {
 			payload = result.VerifiedPayload
-			for i, auth := range b.Signatures.Authorities {
-				if result.Authority == auth {
-					fmt.Printf("[Signed with certificate #%d]\n", i)
-					break
-				}
+			if cert >= 0 {
+				fmt.Printf("[Signed with certificate #%d]\n", cert)
 			}
 		} else {
 			fmt.Println("[Not signed]")
@@ -73,12 +175,12 @@ func DumpExchange(e *bundle.Exchange, b *bundle.Bundle, verifier *signature.Veri
 			return err
 		}
 	}
-	if _, err := fmt.Printf("< [len(Body)]: %d\n", len(e.Response.Body)); err != nil {
+	if _, err := fmt.Printf("< [len(Body)]: %d\n", len(body)); err != nil {
 		return err
 	}
 	if *flagDumpContentText {
 		ctype := e.Response.Header.Get("content-type")
-		if isTextType(ctype) {
+		if mimetype.IsText(ctype) {
 			if _, err := fmt.Print(string(payload)); err != nil {
 				return err
 			}
@@ -94,21 +196,35 @@ func DumpExchange(e *bundle.Exchange, b *bundle.Bundle, verifier *signature.Veri
 	return nil
 }
 
-func isTextType(mimeType string) bool {
-	m, _, err := mime.ParseMediaType(mimeType)
+func run() error {
+	b, ibInfo, err := ReadBundleFromFile(*flagInput)
 	if err != nil {
-		// Since this is a dump tool, we just ignore parse errors.
-		return false
+		return err
 	}
-	return strings.HasPrefix(m, "text/") || m == "application/javascript"
-}
-
-func run() error {
-	b, err := ReadBundleFromFile(*flagInput)
+	es, err := filterExchanges(b.Exchanges)
 	if err != nil {
 		return err
 	}
 
+	if *flagBody {
+		if len(es) != 1 {
+			return fmt.Errorf("-body requires -url or -urlPattern to select exactly one exchange, got %d", len(es))
+		}
+		body, err := es[0].Response.BodyBytes()
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(body)
+		return err
+	}
+	if *flagJSON {
+		return DumpJSON(os.Stdout, b, ibInfo, es)
+	}
+
+	if ibInfo != nil {
+		DumpIntegrityBlock(ibInfo)
+	}
+
 	fmt.Printf("Version: %v\n", b.Version)
 
 	if b.PrimaryURL != nil {
@@ -117,6 +233,18 @@ func run() error {
 	if b.ManifestURL != nil {
 		fmt.Printf("Manifest URL: %v\n", b.ManifestURL)
 	}
+	if len(b.Dependencies) > 0 {
+		fmt.Println("Dependencies:")
+		for _, d := range b.Dependencies {
+			fmt.Printf("  %v -> %v (%s)\n", d.ResourceURL, d.BundleURL, d.LoadType)
+		}
+	}
+	if len(b.Critical) > 0 {
+		fmt.Printf("Critical sections: %v\n", b.Critical)
+	}
+	for _, s := range b.UnknownSections {
+		fmt.Printf("Unknown section: %q (%d bytes)\n", s.Name, len(s.Contents))
+	}
 
 	var verifier *signature.Verifier
 	if b.Signatures != nil {
@@ -135,7 +263,7 @@ func run() error {
 		}
 	}
 
-	for _, e := range b.Exchanges {
+	for _, e := range es {
 		fmt.Println()
 		if err := DumpExchange(e, b, verifier); err != nil {
 			return err
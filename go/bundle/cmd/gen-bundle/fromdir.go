@@ -87,7 +87,10 @@ func (w *responseWriter) WriteHeader(statusCode int) {
 
 // createExchange creates a bundle.Exchange whose request URL is url
 // and response body is the contents of the file. Internally, it uses
-// http.ServeFile to generate a realistic HTTP response for the file.
+// http.ServeFile to generate a realistic HTTP response for the file. The
+// response headers are generated from a HEAD request, and the body of a 200
+// response is streamed from the file when the bundle is written. Other
+// responses are small, and are generated with a GET request.
 func createExchange(file string, url string) (*bundle.Exchange, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -95,18 +98,40 @@ func createExchange(file string, url string) (*bundle.Exchange, error) {
 	}
 	log.Printf("Creating exchange: %s -> %s", file, req.URL)
 
+	headReq := req.Clone(req.Context())
+	headReq.Method = http.MethodHead
 	w := newResponseWriter()
-	http.ServeFile(w, req, file)
+	http.ServeFile(w, headReq, file)
+
+	res := bundle.Response{
+		Status: w.status,
+		Header: w.header,
+	}
+	if w.status == http.StatusOK {
+		// http.ServeFile serves index.html for a directory.
+		bodyFile := file
+		if info, err := os.Stat(file); err == nil && info.IsDir() {
+			bodyFile = filepath.Join(file, "index.html")
+		}
+		res.BodySource, err = bundle.FileBody(bodyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read %s. err: %v", bodyFile, err)
+		}
+	} else {
+		// A HEAD response has no body, so generate the response again with
+		// the GET request to keep the body of e.g. redirects and errors.
+		w = newResponseWriter()
+		http.ServeFile(w, req, file)
+		res.Status = w.status
+		res.Header = w.header
+		res.Body = w.Bytes()
+	}
 
 	return &bundle.Exchange{
 		Request: bundle.Request{
 			URL:    req.URL,
 			Header: req.Header,
 		},
-		Response: bundle.Response{
-			Status: w.status,
-			Header: w.header,
-			Body:   w.Bytes(),
-		},
+		Response: res,
 	}, nil
 }
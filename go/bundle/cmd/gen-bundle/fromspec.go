@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/WICG/webpackage/go/bundle"
+)
+
+// bundleSpec is the JSON input of -spec. For example:
+//
+//	{
+//	  "version": "b2",
+//	  "primaryURL": "https://example.com/",
+//	  "baseURL": "https://example.com/",
+//	  "exchanges": [
+//	    {"url": "/", "file": "dist/index.html"},
+//	    {"url": "/hello.txt", "text": "Hello!", "headers": {"Cache-Control": "no-cache"}},
+//	    {"url": "/old", "status": 301, "headers": {"Location": "/"}}
+//	  ],
+//	  "headerRules": [
+//	    {"match": "https://example.com/**.js", "headers": {"Cache-Control": "max-age=3600"}}
+//	  ]
+//	}
+type bundleSpec struct {
+	Version     string `json:"version"`
+	PrimaryURL  string `json:"primaryURL"`
+	ManifestURL string `json:"manifestURL"`
+	// BaseURL, if set, is used to resolve relative exchange URLs.
+	BaseURL     string            `json:"baseURL"`
+	Exchanges   []*exchangeSpec   `json:"exchanges"`
+	HeaderRules []*headerRuleSpec `json:"headerRules"`
+}
+
+type exchangeSpec struct {
+	URL string `json:"url"`
+	// Status defaults to 200.
+	Status int `json:"status"`
+	// File is the path of the body file, relative to the spec file. At most
+	// one of File and Text can be set.
+	File string  `json:"file"`
+	Text *string `json:"text"`
+	// Headers with an empty value are removed, e.g. "Content-Type": "" for
+	// no Content-Type.
+	Headers map[string]string `json:"headers"`
+}
+
+// headerRuleSpec sets headers of the exchanges whose URL matches the glob
+// pattern Match (see bundle.GlobMatcher). Rules are applied in order, before
+// the headers of each exchange, so exchange headers take precedence.
+type headerRuleSpec struct {
+	Match   string            `json:"match"`
+	Headers map[string]string `json:"headers"`
+}
+
+func readSpec(specFile string) (*bundleSpec, error) {
+	f, err := os.Open(specFile)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open %q: %v", specFile, err)
+	}
+	defer f.Close()
+
+	spec := &bundleSpec{}
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(spec); err != nil {
+		return nil, fmt.Errorf("Failed to parse %q: %v", specFile, err)
+	}
+	return spec, nil
+}
+
+// fromSpec creates the exchanges listed in spec. Body files are resolved
+// relative to specDir.
+func fromSpec(spec *bundleSpec, specDir string) ([]*bundle.Exchange, error) {
+	var baseURL *url.URL
+	if spec.BaseURL != "" {
+		var err error
+		baseURL, err = url.Parse(spec.BaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse base URL %q: %v", spec.BaseURL, err)
+		}
+	}
+
+	var matchers []bundle.URLMatcher
+	for _, r := range spec.HeaderRules {
+		m, err := bundle.GlobMatcher(r.Match)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid header rule pattern %q: %v", r.Match, err)
+		}
+		matchers = append(matchers, m)
+	}
+
+	es := []*bundle.Exchange{}
+	for i, s := range spec.Exchanges {
+		e, err := s.createExchange(baseURL, specDir)
+		if err != nil {
+			return nil, fmt.Errorf("exchanges[%d]: %v", i, err)
+		}
+		for j, r := range spec.HeaderRules {
+			if matchers[j](e.Request.URL) {
+				setHeaders(e.Response.Header, r.Headers)
+			}
+		}
+		setHeaders(e.Response.Header, s.Headers)
+		es = append(es, e)
+	}
+	return es, nil
+}
+
+// setHeaders sets the headers in values, removing those with an empty value.
+func setHeaders(h http.Header, values map[string]string) {
+	for name, value := range values {
+		if value == "" {
+			h.Del(name)
+		} else {
+			h.Set(name, value)
+		}
+	}
+}
+
+func (s *exchangeSpec) createExchange(baseURL *url.URL, specDir string) (*bundle.Exchange, error) {
+	if s.URL == "" {
+		return nil, errors.New("url is missing")
+	}
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URL %q: %v", s.URL, err)
+	}
+	if baseURL != nil {
+		u = baseURL.ResolveReference(u)
+	}
+
+	res := bundle.Response{
+		Status: s.Status,
+		Header: make(http.Header),
+	}
+	if res.Status == 0 {
+		res.Status = http.StatusOK
+	}
+	switch {
+	case s.File != "" && s.Text != nil:
+		return nil, fmt.Errorf("%v: only one of file and text can be specified", u)
+	case s.File != "":
+		path := s.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(specDir, path)
+		}
+		res.BodySource, err = bundle.FileBody(path)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", u, err)
+		}
+		if ctype := mime.TypeByExtension(filepath.Ext(path)); ctype != "" {
+			res.Header.Set("Content-Type", ctype)
+		}
+	case s.Text != nil:
+		res.Body = []byte(*s.Text)
+		res.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	return &bundle.Exchange{
+		Request:  bundle.Request{URL: u},
+		Response: res,
+	}, nil
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/WICG/webpackage/go/bundle"
+	"github.com/WICG/webpackage/go/bundle/version"
+)
+
+// writeSpec writes the spec JSON and the body files to a temporary directory,
+// and returns the path of the spec file.
+func writeSpec(t *testing.T, json string, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	specFile := filepath.Join(dir, "bundle.json")
+	if err := os.WriteFile(specFile, []byte(json), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return specFile
+}
+
+func readBody(t *testing.T, res *bundle.Response) string {
+	body, err := res.BodyBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestFromSpec(t *testing.T) {
+	specFile := writeSpec(t, `{
+  "version": "b2",
+  "baseURL": "https://example.com/",
+  "exchanges": [
+    {"url": "/", "file": "dist/index.html"},
+    {"url": "/app.js", "file": "dist/app.js", "headers": {"Cache-Control": "no-cache"}},
+    {"url": "https://other.example.com/hello.txt", "text": "Hello!"},
+    {"url": "/old", "status": 301, "headers": {"Location": "/"}}
+  ],
+  "headerRules": [
+    {"match": "https://example.com/**.js", "headers": {"Cache-Control": "max-age=3600", "X-Rule": "js"}},
+    {"match": "https://example.com/**", "headers": {"X-Rule": "all"}}
+  ]
+}`, map[string]string{
+		"dist/index.html": "<p>index</p>",
+		"dist/app.js":     "app();",
+	})
+	spec, err := readSpec(specFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es, err := fromSpec(spec, filepath.Dir(specFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		url    string
+		status int
+		header http.Header
+		body   string
+	}{
+		{
+			"https://example.com/", 200,
+			http.Header{"Content-Type": {mime.TypeByExtension(".html")}, "X-Rule": {"all"}},
+			"<p>index</p>",
+		},
+		{
+			// Later rules override earlier ones, and exchange headers override
+			// the rules.
+			"https://example.com/app.js", 200,
+			http.Header{"Content-Type": {mime.TypeByExtension(".js")}, "Cache-Control": {"no-cache"}, "X-Rule": {"all"}},
+			"app();",
+		},
+		{
+			"https://other.example.com/hello.txt", 200,
+			http.Header{"Content-Type": {"text/plain; charset=utf-8"}},
+			"Hello!",
+		},
+		{
+			"https://example.com/old", 301,
+			http.Header{"Location": {"/"}, "X-Rule": {"all"}},
+			"",
+		},
+	}
+	if len(es) != len(want) {
+		t.Fatalf("got %d exchanges, want %d", len(es), len(want))
+	}
+	for i, w := range want {
+		e := es[i]
+		if got := e.Request.URL.String(); got != w.url {
+			t.Errorf("exchanges[%d]: got URL %q, want %q", i, got, w.url)
+		}
+		if e.Response.Status != w.status {
+			t.Errorf("exchanges[%d]: got status %d, want %d", i, e.Response.Status, w.status)
+		}
+		if !reflect.DeepEqual(e.Response.Header, w.header) {
+			t.Errorf("exchanges[%d]: got headers %v, want %v", i, e.Response.Header, w.header)
+		}
+		if got := readBody(t, &e.Response); got != w.body {
+			t.Errorf("exchanges[%d]: got body %q, want %q", i, got, w.body)
+		}
+	}
+}
+
+func TestFromSpecErrors(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		json string
+	}{
+		{"missing url", `{"exchanges": [{"text": "a"}]}`},
+		{"file and text", `{"exchanges": [{"url": "/", "file": "a.txt", "text": "a"}]}`},
+		{"missing file", `{"exchanges": [{"url": "/", "file": "missing.txt"}]}`},
+		{"invalid base URL", `{"baseURL": "%zz", "exchanges": []}`},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			specFile := writeSpec(t, c.json, map[string]string{"a.txt": "a"})
+			spec, err := readSpec(specFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := fromSpec(spec, filepath.Dir(specFile)); err == nil {
+				t.Error("fromSpec unexpectedly succeeded")
+			}
+		})
+	}
+
+	specFile := writeSpec(t, `{"exchanges": [], "unknown": 1}`, nil)
+	if _, err := readSpec(specFile); err == nil {
+		t.Error("readSpec with an unknown field unexpectedly succeeded")
+	}
+}
+
+func TestApplySpecDefaultsBaseURL(t *testing.T) {
+	spec := &bundleSpec{BaseURL: "https://spec.example.com/"}
+	applySpecDefaults(spec)
+	if spec.BaseURL != "https://spec.example.com/" {
+		t.Errorf("got base URL %q without -baseURL", spec.BaseURL)
+	}
+
+	defer func(old string) { *flagBaseURL = old }(*flagBaseURL)
+	if err := flag.Set("baseURL", "https://flag.example.com/"); err != nil {
+		t.Fatal(err)
+	}
+	applySpecDefaults(spec)
+	if spec.BaseURL != "https://flag.example.com/" {
+		t.Errorf("got base URL %q, want the -baseURL value", spec.BaseURL)
+	}
+}
+
+func TestFromExtractedSpec(t *testing.T) {
+	b := &bundle.Bundle{
+		Version:    version.VersionB2,
+		PrimaryURL: mustParseURL(t, "https://example.com/"),
+	}
+	for _, e := range []struct {
+		url    string
+		status int
+		header http.Header
+		body   string
+	}{
+		{"https://example.com/", 200, http.Header{"Content-Type": {"text/html"}}, "<p>index</p>"},
+		{"https://example.com/search?q=a/b", 200, http.Header{"Content-Type": {"application/json"}}, "[]"},
+		{"https://example.com/app.js", 200, http.Header{"Cache-Control": {"no-cache"}}, "app();"},
+		{"https://example.com/old", 301, http.Header{"Location": {"/"}}, ""},
+		{"https://example.com/missing", 404, http.Header{"Content-Type": {"text/plain"}}, "not found"},
+		{"relative/b.txt", 200, http.Header{"Content-Type": {"text/plain"}}, "b"},
+	} {
+		b.Exchanges = append(b.Exchanges, &bundle.Exchange{
+			Request:  bundle.Request{URL: mustParseURL(t, e.url)},
+			Response: bundle.Response{Status: e.status, Header: e.header, Body: []byte(e.body)},
+		})
+	}
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	b, err := bundle.Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := b.ExtractTo(dir); err != nil {
+		t.Fatal(err)
+	}
+	spec, err := readSpec(filepath.Join(dir, bundle.ExtractSpecFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	es, err := fromSpec(spec, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ver, ok := version.Parse(spec.Version)
+	if !ok {
+		t.Fatalf("unknown version %q", spec.Version)
+	}
+	rebuilt := &bundle.Bundle{
+		Version:    ver,
+		PrimaryURL: mustParseURL(t, spec.PrimaryURL),
+		Exchanges:  es,
+	}
+
+	d, err := bundle.Diff(b, rebuilt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Empty() {
+		t.Errorf("the rebuilt bundle differs: %+v", d)
+		for _, e := range d.ChangedExchanges {
+			t.Logf("%s: %+v %+v", e.URL, e.Status, e.Headers)
+		}
+	}
+	// The exchanges of a read bundle are in the order of its index, so
+	// compare with the read bundle written again.
+	var want, got bytes.Buffer
+	if _, err := b.WriteTo(&want); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rebuilt.WriteTo(&got); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Error("the rebuilt bundle is not identical to the original")
+	}
+}
+
+func mustParseURL(t *testing.T, rawURL string) *url.URL {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/url"
+
+	"github.com/WICG/webpackage/go/bundle"
+	"github.com/WICG/webpackage/go/integrityblock/webbundleid"
+	"github.com/WICG/webpackage/go/internal/signingalgorithm"
+)
+
+// iwaManifestPath is the path of the Web App Manifest of an Isolated Web App.
+const iwaManifestPath = "/.well-known/manifest.webmanifest"
+
+func init() {
+	// Go doesn't know the extension of Web App Manifests, which would make
+	// them text/plain.
+	mime.AddExtensionType(".webmanifest", "application/manifest+json")
+}
+
+// iwaCSP is the Content Security Policy an Isolated Web App must be served
+// with. See https://github.com/WICG/isolated-web-apps/blob/main/README.md.
+const iwaCSP = "base-uri 'none'; default-src 'self'; object-src 'none'; " +
+	"frame-src 'self' https: blob: data:; connect-src 'self' https: wss: blob: data:; " +
+	"script-src 'self' 'wasm-unsafe-eval'; img-src 'self' https: blob: data:; " +
+	"media-src 'self' https: blob: data:; font-src 'self' blob: data:; " +
+	"style-src 'self' 'unsafe-inline'; require-trusted-types-for 'script'; " +
+	"frame-ancestors 'self';"
+
+// iwaRequiredHeaders are the cross-origin isolation headers an Isolated Web
+// App must be served with. Responses can't have other values for them.
+var iwaRequiredHeaders = []struct{ name, value string }{
+	{"Cross-Origin-Opener-Policy", "same-origin"},
+	{"Cross-Origin-Embedder-Policy", "require-corp"},
+	{"Cross-Origin-Resource-Policy", "same-origin"},
+}
+
+// readIWAPublicKey reads the Ed25519 or ECDSA P-256 public key of the Isolated
+// Web App from -privateKey or -publicKey.
+func readIWAPublicKey() (crypto.PublicKey, error) {
+	switch {
+	case *flagPrivateKey != "" && *flagPublicKey != "":
+		return nil, errors.New("Please specify only one of -privateKey and -publicKey.")
+	case *flagPrivateKey != "":
+		text, err := ioutil.ReadFile(*flagPrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read the private key. err: %v", err)
+		}
+		privKey, err := signingalgorithm.ParsePrivateKey(text)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse the private key. err: %v", err)
+		}
+		// Both ed25519.PrivateKey and *ecdsa.PrivateKey are crypto.Signers.
+		return privKey.(crypto.Signer).Public(), nil
+	case *flagPublicKey != "":
+		text, err := ioutil.ReadFile(*flagPublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read the public key. err: %v", err)
+		}
+		pubKey, err := signingalgorithm.ParsePublicKey(text)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse the public key. err: %v", err)
+		}
+		return pubKey, nil
+	default:
+		return nil, errors.New("-iwa requires -privateKey or -publicKey.")
+	}
+}
+
+// iwaOrigin returns the isolated-app:// origin of the Isolated Web App signed
+// with publicKey.
+func iwaOrigin(publicKey crypto.PublicKey) (*url.URL, error) {
+	webBundleId, err := webbundleid.GetWebBundleId(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &url.URL{Scheme: "isolated-app", Host: webBundleId, Path: "/"}, nil
+}
+
+// applyIWA checks that es form an Isolated Web App served from origin, and
+// adds the response headers it requires.
+func applyIWA(es []*bundle.Exchange, origin *url.URL) error {
+	hasManifest := false
+	for _, e := range es {
+		u := e.Request.URL
+		if u.Scheme != origin.Scheme || u.Host != origin.Host {
+			return fmt.Errorf("%v is not in the origin of the Isolated Web App %v", u, origin)
+		}
+		if u.Path == iwaManifestPath && e.Response.Status == 200 {
+			hasManifest = true
+		}
+
+		h := e.Response.Header
+		for _, rh := range iwaRequiredHeaders {
+			if v := h.Get(rh.name); v != "" && v != rh.value {
+				return fmt.Errorf("%v: %s must be %q, got %q", u, rh.name, rh.value, v)
+			}
+			h.Set(rh.name, rh.value)
+		}
+		if h.Get("Content-Security-Policy") == "" {
+			h.Set("Content-Security-Policy", iwaCSP)
+		}
+	}
+	if !hasManifest {
+		return fmt.Errorf("An Isolated Web App must have a manifest at %s", iwaManifestPath)
+	}
+	return nil
+}
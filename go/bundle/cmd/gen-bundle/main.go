@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/WICG/webpackage/go/bundle"
@@ -23,31 +24,133 @@ func (h *headerArgs) Set(value string) error {
 	return nil
 }
 
+type dependencyArgs []string
+
+func (d *dependencyArgs) String() string {
+	return fmt.Sprintf("%v", *d)
+}
+
+func (d *dependencyArgs) Set(value string) error {
+	*d = append(*d, value)
+	return nil
+}
+
+// parseDependency parses a -dependency flag value of the form
+// "resourceURL,bundleURL[,loadType]".
+func parseDependency(value string) (*bundle.Dependency, error) {
+	chunks := strings.Split(value, ",")
+	if len(chunks) != 2 && len(chunks) != 3 {
+		return nil, fmt.Errorf("invalid dependency %q: expected resourceURL,bundleURL[,loadType]", value)
+	}
+	resourceURL, err := url.Parse(strings.TrimSpace(chunks[0]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse resource URL of dependency %q: %v", value, err)
+	}
+	bundleURL, err := url.Parse(strings.TrimSpace(chunks[1]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse bundle URL of dependency %q: %v", value, err)
+	}
+	loadType := bundle.LoadTypePreload
+	if len(chunks) == 3 {
+		loadType = bundle.LoadType(strings.TrimSpace(chunks[2]))
+	}
+	d := &bundle.Dependency{ResourceURL: resourceURL, BundleURL: bundleURL, LoadType: loadType}
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
 var (
 	flagVersion      = flag.String("version", string(version.VersionB2), "The webbundle format version. Possible values are: 'b1' and 'b2'")
 	flagHar          = flag.String("har", "", "HTTP Archive (HAR) input file")
 	flagDir          = flag.String("dir", "", "Input directory")
-	flagBaseURL      = flag.String("baseURL", "", "Base URL (used with -dir)")
+	flagBaseURL      = flag.String("baseURL", "", "Base URL (used with -dir and -spec)")
 	flagPrimaryURL   = flag.String("primaryURL", "", "Primary URL")
 	flagManifestURL  = flag.String("manifestURL", "", "Manifest URL")
 	flagOutput       = flag.String("o", "out.wbn", "Webbundle output file")
 	flagURLList      = flag.String("URLList", "", "URL list file")
-	flagIgnoreErrors = flag.Bool("ignoreErrors", false, "Do not reject invalid input arguments")
+	flagSpec         = flag.String("spec", "", "JSON file describing the bundle")
+	flagReproducible = flag.Bool("reproducible", false, "Generate the same bundle from the same input, by sorting exchanges and replacing Date and Last-Modified headers with $SOURCE_DATE_EPOCH (or removing them if it is not set)")
+	flagIgnoreErrors = flag.Bool("ignoreErrors", false, "Report problems of the bundle as warnings instead of failing")
+	flagIWA          = flag.Bool("iwa", false, "Generate an Isolated Web App, whose origin is derived from -privateKey or -publicKey")
+	flagPrivateKey   = flag.String("privateKey", "", "Ed25519 or ECDSA P-256 private key PEM file of the Isolated Web App (used with -iwa)")
+	flagPublicKey    = flag.String("publicKey", "", "Ed25519 or ECDSA P-256 public key PEM file of the Isolated Web App (used with -iwa)")
 
 	flagHeaderOverride = headerArgs{}
+	flagDependency     = dependencyArgs{}
 )
 
 func init() {
 	flag.Var(&flagHeaderOverride, "headerOverride", "Set additional response header, replacing any existing values")
+	flag.Var(&flagDependency, "dependency", "Declare that a resource is loaded from another bundle, as resourceURL,bundleURL[,preload|lazy]")
+}
+
+// applySpecDefaults uses the version, primary URL and manifest URL of spec
+// for the flags not given on the command line. -baseURL, if given, overrides
+// the base URL of spec.
+func applySpecDefaults(spec *bundleSpec) {
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	if given["baseURL"] {
+		spec.BaseURL = *flagBaseURL
+	}
+	if !given["version"] && spec.Version != "" {
+		*flagVersion = spec.Version
+	}
+	if !given["primaryURL"] && spec.PrimaryURL != "" {
+		*flagPrimaryURL = spec.PrimaryURL
+	}
+	if !given["manifestURL"] && spec.ManifestURL != "" {
+		*flagManifestURL = spec.ManifestURL
+	}
 }
 
 func main() {
 	flag.Parse()
 
+	var spec *bundleSpec
+	if *flagSpec != "" {
+		if *flagHar != "" || *flagDir != "" || *flagURLList != "" {
+			log.Fatal("Error: -spec cannot be combined with -har, -dir or -URLList")
+		}
+		var err error
+		spec, err = readSpec(*flagSpec)
+		if err != nil {
+			log.Fatal(err)
+		}
+		applySpecDefaults(spec)
+	}
+
 	ver, ok := version.Parse(*flagVersion)
 	if !ok {
 		log.Fatalf("Error: failed to parse version %q\n", *flagVersion)
 	}
+	var iwaOriginURL *url.URL
+	if *flagIWA {
+		if ver != version.VersionB2 {
+			log.Fatalf("Error: Isolated Web Apps must use version %s", version.VersionB2)
+		}
+		publicKey, err := readIWAPublicKey()
+		if err != nil {
+			log.Fatal(err)
+		}
+		iwaOriginURL, err = iwaOrigin(publicKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *flagBaseURL != "" && *flagBaseURL != iwaOriginURL.String() {
+			log.Fatalf("Error: -baseURL must be %v for the Isolated Web App", iwaOriginURL)
+		}
+		*flagBaseURL = iwaOriginURL.String()
+		if spec != nil && spec.BaseURL == "" {
+			spec.BaseURL = iwaOriginURL.String()
+		}
+		if *flagPrimaryURL == "" {
+			*flagPrimaryURL = iwaOriginURL.String()
+		}
+		log.Printf("Web Bundle ID: %s", iwaOriginURL.Host)
+	}
 	if *flagPrimaryURL == "" && ver.HasPrimaryURLFieldInHeader() {
 		fmt.Fprintln(os.Stderr, "Please specify -primaryURL or change your bundle version to a newer one.")
 		flag.Usage()
@@ -72,6 +175,14 @@ func main() {
 
 	b := &bundle.Bundle{Version: ver, PrimaryURL: parsedPrimaryURL, ManifestURL: parsedManifestURL}
 
+	for _, value := range flagDependency {
+		d, err := parseDependency(value)
+		if err != nil {
+			log.Fatal(err)
+		}
+		b.Dependencies = append(b.Dependencies, d)
+	}
+
 	if *flagHar != "" {
 		if *flagBaseURL != "" {
 			fmt.Fprintln(os.Stderr, "Warning: -baseURL is ignored when input is HAR.")
@@ -100,8 +211,14 @@ func main() {
 			log.Fatal(err)
 		}
 		b.Exchanges = es
+	} else if spec != nil {
+		es, err := fromSpec(spec, filepath.Dir(*flagSpec))
+		if err != nil {
+			log.Fatal(err)
+		}
+		b.Exchanges = es
 	} else {
-		fmt.Fprintln(os.Stderr, "Please specify one of -har, -dir, or -URLList.")
+		fmt.Fprintln(os.Stderr, "Please specify one of -har, -dir, -URLList, or -spec.")
 		flag.Usage()
 		return
 	}
@@ -113,10 +230,29 @@ func main() {
 		}
 	}
 
-	if !*flagIgnoreErrors {
-		if err := b.Validate(); err != nil {
+	if iwaOriginURL != nil {
+		if err := applyIWA(b.Exchanges, iwaOriginURL); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *flagReproducible {
+		date, err := sourceDateEpoch()
+		if err != nil {
 			log.Fatal(err)
 		}
+		makeReproducible(b.Exchanges, date)
+	}
+
+	report := bundle.Lint(b)
+	for _, f := range report {
+		if *flagIgnoreErrors && f.Severity == bundle.SeverityError {
+			f.Severity = bundle.SeverityWarning
+		}
+		fmt.Fprintln(os.Stderr, f)
+	}
+	if report.HasErrors() {
+		log.Fatal("The bundle has errors. Use -ignoreErrors to write it anyway.")
 	}
 
 	fo, err := os.OpenFile(*flagOutput, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/WICG/webpackage/go/bundle"
+)
+
+// timeDependentHeaders are the response headers that make bundles generated
+// from the same input differ over time.
+var timeDependentHeaders = []string{"Date", "Last-Modified"}
+
+// sourceDateEpoch returns the time in the SOURCE_DATE_EPOCH environment
+// variable (https://reproducible-builds.org/specs/source-date-epoch/), or
+// nil if it is not set.
+func sourceDateEpoch() (*time.Time, error) {
+	v, ok := os.LookupEnv("SOURCE_DATE_EPOCH")
+	if !ok || v == "" {
+		return nil, nil
+	}
+	sec, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid SOURCE_DATE_EPOCH %q: %v", v, err)
+	}
+	t := time.Unix(sec, 0).UTC()
+	return &t, nil
+}
+
+// makeReproducible sorts es by URL and Variant-Key, and replaces the values
+// of the time-dependent headers with date, or removes them if date is nil.
+// Header order needs no treatment, since the encoder writes headers in the
+// canonical CBOR order.
+func makeReproducible(es []*bundle.Exchange, date *time.Time) {
+	sort.SliceStable(es, func(i, j int) bool {
+		ui, uj := es[i].Request.URL.String(), es[j].Request.URL.String()
+		if ui != uj {
+			return ui < uj
+		}
+		return es[i].Response.Header.Get("Variant-Key") < es[j].Response.Header.Get("Variant-Key")
+	})
+	for _, e := range es {
+		for _, name := range timeDependentHeaders {
+			if e.Response.Header.Get(name) == "" {
+				continue
+			}
+			if date != nil {
+				e.Response.Header.Set(name, date.Format(http.TimeFormat))
+			} else {
+				e.Response.Header.Del(name)
+			}
+		}
+	}
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"crypto"
+	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
@@ -28,51 +32,70 @@ func writeOutput(bundleFile io.ReadSeeker, integrityBlockBytes []byte, originalI
 	return nil
 }
 
-func readPublicEd25519KeyFromFile(path string) (ed25519.PublicKey, error) {
+// readPublicKeyFromFile reads an Ed25519 or ECDSA P-256 public key.
+func readPublicKeyFromFile(path string) (crypto.PublicKey, error) {
 	pubkeytext, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, errors.New("SignIntegrityBlock: Unable to read the public key.")
 	}
 	pubKey, err := signingalgorithm.ParsePublicKey(pubkeytext)
-
-	ed25519pubKey, ok := pubKey.(ed25519.PublicKey)
-	if !ok {
-		return nil, errors.New("SignIntegrityBlock: Public key is not Ed25519 type.")
+	if err != nil {
+		return nil, fmt.Errorf("SignIntegrityBlock: Unable to parse the public key. err: %v", err)
 	}
-	return ed25519pubKey, nil
+	return pubKey, nil
 }
 
-func readAndParseEd25519PrivateKey(path string) (ed25519.PrivateKey, error) {
+// readAndParsePrivateKey reads an Ed25519 or ECDSA private key. Both are crypto.Signers.
+func readAndParsePrivateKey(path string) (crypto.Signer, error) {
 	privKey, err := readPrivateKeyFromFile(path)
 	if err != nil {
 		return nil, errors.New("SignIntegrityBlock: Unable to read the private key.")
 	}
 
-	ed25519privKey, ok := privKey.(ed25519.PrivateKey)
-	if !ok {
-		return nil, errors.New("SignIntegrityBlock: Private key is not Ed25519 type.")
+	switch privKey.(type) {
+	case ed25519.PrivateKey, *ecdsa.PrivateKey:
+		return privKey.(crypto.Signer), nil
+	default:
+		return nil, errors.New("SignIntegrityBlock: Private key is neither Ed25519 nor ECDSA type.")
+	}
+}
+
+// newParsedKeySigningStrategy returns the signing strategy for an Ed25519 or ECDSA P-256 private key.
+func newParsedKeySigningStrategy(privKey crypto.Signer) (integrityblock.ISigningStrategy, error) {
+	switch key := privKey.(type) {
+	case ed25519.PrivateKey:
+		return integrityblock.NewParsedEd25519KeySigningStrategy(key), nil
+	case *ecdsa.PrivateKey:
+		return integrityblock.NewParsedEcdsaP256KeySigningStrategy(key)
+	default:
+		return nil, fmt.Errorf("SignIntegrityBlock: Unsupported private key type: %T", privKey)
 	}
-	return ed25519privKey, nil
 }
 
 func DumpWebBundleIdFromPrivateKey() error {
-	ed25519privKey, err := readAndParseEd25519PrivateKey(*dumpIdFlagPrivateKey)
+	privKey, err := readAndParsePrivateKey(*dumpIdFlagPrivateKey)
 	if err != nil {
 		return err
 	}
 
-	webBundleId := webbundleid.GetWebBundleId(ed25519privKey.Public().(ed25519.PublicKey))
+	webBundleId, err := webbundleid.GetWebBundleId(privKey.Public())
+	if err != nil {
+		return err
+	}
 	fmt.Printf("Web Bundle ID: %s\n", webBundleId)
 	return nil
 }
 
 func DumpWebBundleIdFromPublicKey() error {
-	ed25519pubKey, err := readPublicEd25519KeyFromFile(*dumpIdFlagPublicKey)
+	pubKey, err := readPublicKeyFromFile(*dumpIdFlagPublicKey)
 	if err != nil {
 		return err
 	}
 
-	webBundleId := webbundleid.GetWebBundleId(ed25519pubKey)
+	webBundleId, err := webbundleid.GetWebBundleId(pubKey)
+	if err != nil {
+		return err
+	}
 	fmt.Printf("Web Bundle ID: %s\n", webBundleId)
 	return nil
 }
@@ -85,6 +108,21 @@ func DumpWebBundleId() error {
 	}
 }
 
+// hasSignatureMatchingWebBundleId reports whether the Web Bundle ID declared by
+// the integrity block can be derived from the public key of any signature.
+func hasSignatureMatchingWebBundleId(integrityBlock *integrityblock.IntegrityBlock) bool {
+	for _, is := range integrityBlock.SignatureStack {
+		publicKey, err := is.PublicKey()
+		if err != nil {
+			continue
+		}
+		if webBundleId, err := webbundleid.GetWebBundleId(publicKey); err == nil && webBundleId == integrityBlock.WebBundleId() {
+			return true
+		}
+	}
+	return false
+}
+
 // SignWithIntegrityBlockWithCmdFlags is just a wrapper function for `SignWithIntegrityBlock`
 // function containing the actual logic so that it can be easily exported without having
 // to rely on reading and writing to files specified to be read from the CMD tool flags.
@@ -105,18 +143,30 @@ func SignWithIntegrityBlockWithCmdFlags(signingStrategy integrityblock.ISigningS
 	}
 	defer signedBundleFile.Close()
 
-	return SignWithIntegrityBlock(bundleFile, signedBundleFile, signingStrategy)
+	return SignWithIntegrityBlock(bundleFile, signedBundleFile, signingStrategy, *ibFlagReplaceSignatures, *ibFlagWebBundleId)
 }
 
 // SignWithIntegrityBlock creates a CBOR integrity block containing a signature
-// matching the hash of the web bundle read from `bundleFileIn`. Finally it
-// writes the new signed web bundle into `bundleFileOut`. More details can be
-// found in [Integrity Block Explainer](https://github.com/WICG/webpackage/blob/main/explainers/integrity-signature.md).
-func SignWithIntegrityBlock(bundleFileIn, bundleFileOut *os.File, signingStrategy integrityblock.ISigningStrategy) error {
+// matching the hash of the web bundle read from `bundleFileIn`. If the web
+// bundle is already signed, the new signature is added on top of the existing
+// signature stack, unless `replaceSignatures` is true, in which case the
+// existing signatures are removed. If `webBundleId` is not empty, the integrity
+// block is of version 2 and declares it as the Web Bundle ID. Finally it writes
+// the new signed web bundle into `bundleFileOut`. More details can be found in
+// [Integrity Block Explainer](https://github.com/WICG/webpackage/blob/main/explainers/integrity-signature.md).
+func SignWithIntegrityBlock(bundleFileIn, bundleFileOut *os.File, signingStrategy integrityblock.ISigningStrategy, replaceSignatures bool, webBundleId string) error {
 	integrityBlock, offset, err := integrityblock.ObtainIntegrityBlock(bundleFileIn)
 	if err != nil {
 		return err
 	}
+	if replaceSignatures {
+		integrityBlock.RemoveSignatures()
+	}
+	if webBundleId != "" {
+		if err := integrityBlock.SetWebBundleId(webBundleId); err != nil {
+			return err
+		}
+	}
 
 	webBundleHash, err := integrityblock.ComputeWebBundleSha512(bundleFileIn, offset)
 	if err != nil {
@@ -129,14 +179,21 @@ func SignWithIntegrityBlock(bundleFileIn, bundleFileOut *os.File, signingStrateg
 		IntegrityBlock:  integrityBlock,
 	}
 
-	ed25519publicKey, err := ibs.SigningStrategy.GetPublicKey()
+	publicKey, err := ibs.SigningStrategy.GetPublicKey()
 	if err != nil {
 		return err
 	}
 
-	signatureAttributes := integrityblock.GenerateSignatureAttributesWithPublicKey(ed25519publicKey)
+	if integrityBlock.HasSignatureWithPublicKey(publicKey) {
+		return errors.New("SignIntegrityBlock: The web bundle is already signed with this key.")
+	}
+
+	signatureAttributes, err := integrityblock.GenerateSignatureAttributesWithPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
 
-	err = ibs.SignAndAddNewSignature(ed25519publicKey, signatureAttributes)
+	err = ibs.SignAndAddNewSignature(publicKey, signatureAttributes)
 	if err != nil {
 		return err
 	}
@@ -152,8 +209,65 @@ func SignWithIntegrityBlock(bundleFileIn, bundleFileOut *os.File, signingStrateg
 		return err
 	}
 
-	webBundleId := webbundleid.GetWebBundleId(ed25519publicKey)
-	fmt.Println("Web Bundle ID: " + webBundleId)
+	if integrityBlock.IsVersionB2() {
+		fmt.Println("Web Bundle ID: " + integrityBlock.WebBundleId())
+		if !hasSignatureMatchingWebBundleId(integrityBlock) {
+			fmt.Println("Note: The Web Bundle ID doesn't match any signing key. Verifying the bundle requires a trusted public key for the Web Bundle ID.")
+		}
+	} else {
+		webBundleId, err := webbundleid.GetWebBundleId(publicKey)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Web Bundle ID: " + webBundleId)
+	}
 
 	return writeOutput(bundleFileIn, integrityBlockBytes, offset, bundleFileOut)
 }
+
+// encodePublicKey returns the base64-encoded public key as stored in the
+// signature attributes, i.e. in compressed form for ECDSA P-256 keys.
+func encodePublicKey(publicKey crypto.PublicKey) string {
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		return base64.StdEncoding.EncodeToString(key)
+	case *ecdsa.PublicKey:
+		return base64.StdEncoding.EncodeToString(elliptic.MarshalCompressed(key.Curve, key.X, key.Y))
+	default:
+		return fmt.Sprintf("%v", publicKey)
+	}
+}
+
+// VerifyIntegrityBlock verifies all signatures of the integrity block of the
+// web bundle given by the flags, and prints the Web Bundle ID and the public
+// keys of the signatures. It fails if a signature doesn't verify, or if the
+// Web Bundle ID differs from the expected one.
+func VerifyIntegrityBlock() error {
+	bundleFile, err := os.Open(*verifyFlagInput)
+	if err != nil {
+		return err
+	}
+	defer bundleFile.Close()
+
+	var trustedPublicKey crypto.PublicKey
+	if *verifyFlagPublicKey != "" {
+		trustedPublicKey, err = readPublicKeyFromFile(*verifyFlagPublicKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	result, err := integrityblock.VerifyWithTrustedPublicKey(bundleFile, trustedPublicKey)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Web Bundle ID: " + result.WebBundleId)
+	for i, publicKey := range result.PublicKeys {
+		fmt.Printf("Signature #%d: verified with public key %s\n", i, encodePublicKey(publicKey))
+	}
+
+	if *verifyFlagWebBundleId != "" && *verifyFlagWebBundleId != result.WebBundleId {
+		return fmt.Errorf("VerifyIntegrityBlock: Expected Web Bundle ID %s, got %s.", *verifyFlagWebBundleId, result.WebBundleId)
+	}
+	return nil
+}
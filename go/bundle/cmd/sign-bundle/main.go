@@ -15,8 +15,25 @@ const (
 	signaturesSectionSubCmdName = "signatures-section"
 	integrityBlockSubCmdName    = "integrity-block"
 	dumpWebBundleIdSubCmdName   = "dump-id"
+	verifySubCmdName            = "verify"
 )
 
+const (
+	signingStrategyPrivateKey = "privateKey"
+	signingStrategyCommand    = "command"
+)
+
+type stringArgs []string
+
+func (s *stringArgs) String() string {
+	return fmt.Sprintf("%v", *s)
+}
+
+func (s *stringArgs) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
 var (
 	signedExchangesCmd  = flag.NewFlagSet(signaturesSectionSubCmdName, flag.ExitOnError)
 	sxgFlagInput        = signedExchangesCmd.String("i", "in.wbn", "Webbundle input file")
@@ -30,12 +47,21 @@ var (
 )
 
 var (
-	integrityBlockCmd = flag.NewFlagSet(integrityBlockSubCmdName, flag.ExitOnError)
-	ibFlagInput       = integrityBlockCmd.String("i", "in.wbn", "Webbundle input file")
-	ibFlagOutput      = integrityBlockCmd.String("o", "out.wbn", "Webbundle output file")
-	ibFlagPrivateKey  = integrityBlockCmd.String("privateKey", "privatekey.pem", "Private key PEM file")
+	integrityBlockCmd       = flag.NewFlagSet(integrityBlockSubCmdName, flag.ExitOnError)
+	ibFlagInput             = integrityBlockCmd.String("i", "in.wbn", "Webbundle input file")
+	ibFlagOutput            = integrityBlockCmd.String("o", "out.wbn", "Webbundle output file")
+	ibFlagPrivateKey        = integrityBlockCmd.String("privateKey", "privatekey.pem", "Private key PEM file (used with -signingStrategy=privateKey)")
+	ibFlagSigningStrategy   = integrityBlockCmd.String("signingStrategy", signingStrategyPrivateKey, "How to sign: 'privateKey' signs with -privateKey, 'command' delegates signing to -signCommand")
+	ibFlagSignCommand       = integrityBlockCmd.String("signCommand", "", "External signing program (used with -signingStrategy=command). It is run with the -signCommandArg arguments and 'public-key' to print the public key PEM, or 'sign' to sign the data from stdin")
+	ibFlagSignCommandArgs   = stringArgs{}
+	ibFlagReplaceSignatures = integrityBlockCmd.Bool("replaceSignatures", false, "Remove the existing signatures of a signed web bundle instead of adding a new one to them (e.g. after a key rotation)")
+	ibFlagWebBundleId       = integrityBlockCmd.String("webBundleId", "", "Web Bundle ID to declare in a version 2 integrity block. If empty, a version b1 integrity block is created, whose Web Bundle ID is derived from the signing key")
 )
 
+func init() {
+	integrityBlockCmd.Var(&ibFlagSignCommandArgs, "signCommandArg", "Argument passed to -signCommand before 'public-key' or 'sign'. Can be repeated.")
+}
+
 const flagNamePublicKey = "publicKey"
 
 var (
@@ -44,6 +70,13 @@ var (
 	dumpIdFlagPublicKey  = dumpWebBundleIdCmd.String(flagNamePublicKey, "", "Public key PEM file whose corresponding Web Bundle ID is wanted.")
 )
 
+var (
+	verifyCmd             = flag.NewFlagSet(verifySubCmdName, flag.ExitOnError)
+	verifyFlagInput       = verifyCmd.String("i", "in.wbn", "Webbundle input file signed with integrity block")
+	verifyFlagWebBundleId = verifyCmd.String("webBundleId", "", "Fail unless the bundle has this Web Bundle ID")
+	verifyFlagPublicKey   = verifyCmd.String(flagNamePublicKey, "", "Public key PEM file trusted for the Web Bundle ID, e.g. after a key rotation. If given, the bundle must be signed with it, and the declared Web Bundle ID doesn't have to match any signature")
+)
+
 // isFlagPassed is a helper function to check if the given flag was provided. Note that this needs to be called after flag.Parse.
 func isFlagPassed(flags *flag.FlagSet, name string) bool {
 	found := false
@@ -65,23 +98,36 @@ func run() error {
 	case integrityBlockSubCmdName:
 		integrityBlockCmd.Parse(os.Args[2:])
 
-		// TODO(sonkkeli): Add parsing for the new `signingStrategy` flag and
-		// make another switch case for the different types of signing.
-
-		ed25519privKey, err := readAndParseEd25519PrivateKey(*ibFlagPrivateKey)
-		if err != nil {
-			return err
+		var bss integrityblock.ISigningStrategy
+		switch *ibFlagSigningStrategy {
+		case signingStrategyPrivateKey:
+			privKey, err := readAndParsePrivateKey(*ibFlagPrivateKey)
+			if err != nil {
+				return err
+			}
+			if bss, err = newParsedKeySigningStrategy(privKey); err != nil {
+				return err
+			}
+		case signingStrategyCommand:
+			var err error
+			if bss, err = integrityblock.NewExternalCommandSigningStrategy(*ibFlagSignCommand, ibFlagSignCommandArgs...); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("Unknown signing strategy %q, try '%s' or '%s'", *ibFlagSigningStrategy, signingStrategyPrivateKey, signingStrategyCommand)
 		}
-
-		var bss integrityblock.ISigningStrategy = integrityblock.NewParsedEd25519KeySigningStrategy(ed25519privKey)
 		return SignWithIntegrityBlockWithCmdFlags(bss)
 
 	case dumpWebBundleIdSubCmdName:
 		dumpWebBundleIdCmd.Parse(os.Args[2:])
 		return DumpWebBundleId()
 
+	case verifySubCmdName:
+		verifyCmd.Parse(os.Args[2:])
+		return VerifyIntegrityBlock()
+
 	default:
-		return errors.New(fmt.Sprintf("Unknown subcommand, try '%s', '%s' or '%s'", signaturesSectionSubCmdName, integrityBlockSubCmdName, dumpWebBundleIdSubCmdName))
+		return errors.New(fmt.Sprintf("Unknown subcommand, try '%s', '%s', '%s' or '%s'", signaturesSectionSubCmdName, integrityBlockSubCmdName, dumpWebBundleIdSubCmdName, verifySubCmdName))
 	}
 }
 
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/WICG/webpackage/go/bundle"
+)
+
+var (
+	flagInput  = flag.String("i", "in.wbn", "Webbundle input file")
+	flagOutput = flag.String("o", "out", "Output directory")
+)
+
+func run() error {
+	fi, err := os.Open(*flagInput)
+	if err != nil {
+		return fmt.Errorf("Failed to open input file %q for reading. err: %v", *flagInput, err)
+	}
+	defer fi.Close()
+	b, err := bundle.Read(fi)
+	if err != nil {
+		return fmt.Errorf("%s: %v", *flagInput, err)
+	}
+	if err := b.ExtractTo(*flagOutput); err != nil {
+		return err
+	}
+	log.Printf("Extracted %d exchanges to %s", len(b.Exchanges), *flagOutput)
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,95 @@
+package bundle
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/WICG/webpackage/go/bundle/version"
+)
+
+// ConvertTo returns a copy of b in the format version ver. The primary URL
+// is moved between the header (b1) and the primary section (b2) as needed.
+//
+// Features that ver does not support are dropped, and each of them is
+// described in the returned list, which is empty if the conversion is
+// lossless:
+//   - the manifest URL, if ver has no manifest section;
+//   - all but the default variant of a resource with Variants, if ver does
+//     not support variants. The default variant is the one a request without
+//     any headers would get (see Lookup), and its Variants and Variant-Key
+//     headers are removed;
+//   - the signatures section, since the signed messages include the
+//     version.
+//
+// b itself is not modified.
+func (b *Bundle) ConvertTo(ver version.Version) (*Bundle, []string, error) {
+	if _, ok := version.Parse(string(ver)); !ok {
+		return nil, nil, fmt.Errorf("bundle: unsupported version %q", ver)
+	}
+	if ver.HasPrimaryURLFieldInHeader() && b.PrimaryURL == nil {
+		return nil, nil, fmt.Errorf("bundle: version %s requires a primary URL", ver)
+	}
+
+	converted := *b
+	converted.Version = ver
+	if ver == b.Version {
+		converted.Exchanges = append([]*Exchange(nil), b.Exchanges...)
+		return &converted, nil, nil
+	}
+
+	var lost []string
+	if b.ManifestURL != nil && !ver.SupportsManifestSection() {
+		converted.ManifestURL = nil
+		lost = append(lost, fmt.Sprintf("manifest URL %v: version %s has no manifest section", b.ManifestURL, ver))
+	}
+	if b.Signatures != nil {
+		converted.Signatures = nil
+		lost = append(lost, fmt.Sprintf("signatures section: the signatures are only valid for version %s", b.Version))
+	}
+
+	es, dropped, err := b.exchangesForVersion(ver)
+	if err != nil {
+		return nil, nil, err
+	}
+	converted.Exchanges = es
+	return &converted, append(lost, dropped...), nil
+}
+
+// exchangesForVersion returns the exchanges of b, keeping only the default
+// variant of each resource if ver does not support variants.
+func (b *Bundle) exchangesForVersion(ver version.Version) ([]*Exchange, []string, error) {
+	if ver.SupportsVariants() {
+		return append([]*Exchange(nil), b.Exchanges...), nil, nil
+	}
+
+	count := make(map[string]int)
+	for _, e := range b.Exchanges {
+		count[e.Request.URL.String()]++
+	}
+
+	var es []*Exchange
+	var lost []string
+	for _, e := range b.Exchanges {
+		if count[e.Request.URL.String()] == 1 {
+			es = append(es, e)
+			continue
+		}
+		if e.Response.Header.Get("Variants") == "" {
+			return nil, nil, fmt.Errorf("bundle: %v has multiple exchanges without Variants header", e.Request.URL)
+		}
+		def, err := b.Lookup(e.Request.URL, http.Header{})
+		if err != nil {
+			return nil, nil, err
+		}
+		if e != def {
+			lost = append(lost, fmt.Sprintf("%v: variant %q: version %s does not support variants", e.Request.URL, e.Response.Header.Get("Variant-Key"), ver))
+			continue
+		}
+		kept := *e
+		kept.Response.Header = e.Response.Header.Clone()
+		kept.Response.Header.Del("Variants")
+		kept.Response.Header.Del("Variant-Key")
+		es = append(es, &kept)
+	}
+	return es, lost, nil
+}
@@ -0,0 +1,94 @@
+package bundle_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	. "github.com/WICG/webpackage/go/bundle"
+	"github.com/WICG/webpackage/go/bundle/version"
+)
+
+func TestConvertTo(t *testing.T) {
+	b := createTestBundle(t, version.VersionB1)
+	b.ManifestURL = urlMustParse("https://bundle.example.com/manifest.json")
+
+	converted, lost, err := b.ConvertTo(version.VersionB2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"manifest URL https://bundle.example.com/manifest.json: version b2 has no manifest section",
+		"signatures section: the signatures are only valid for version b1",
+	}
+	if !reflect.DeepEqual(lost, want) {
+		t.Errorf("lost: got %q, want %q", lost, want)
+	}
+	if b.Version != version.VersionB1 || b.ManifestURL == nil || b.Signatures == nil {
+		t.Error("ConvertTo modified the original bundle")
+	}
+
+	var buf bytes.Buffer
+	if _, err := converted.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != version.VersionB2 || got.PrimaryURL.String() != b.PrimaryURL.String() {
+		t.Errorf("got version %s and primary URL %v", got.Version, got.PrimaryURL)
+	}
+	if got.ManifestURL != nil || got.Signatures != nil {
+		t.Error("the manifest URL and the signatures should be dropped")
+	}
+	if !reflect.DeepEqual(got.Exchanges, b.Exchanges) {
+		t.Errorf("exchanges: got %v, want %v", got.Exchanges, b.Exchanges)
+	}
+
+	back, lost, err := got.ConvertTo(version.VersionB1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lost) != 0 {
+		t.Errorf("lost: got %q, want nothing", lost)
+	}
+	if back.Version != version.VersionB1 {
+		t.Errorf("got version %s", back.Version)
+	}
+}
+
+func TestConvertToVariants(t *testing.T) {
+	b := createTestBundleWithVariants(version.VersionB1)
+	converted, lost, err := b.ConvertTo(version.VersionB2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`https://variants.example.com/: variant "ja": version b2 does not support variants`}
+	if !reflect.DeepEqual(lost, want) {
+		t.Errorf("lost: got %q, want %q", lost, want)
+	}
+	if len(converted.Exchanges) != 1 {
+		t.Fatalf("got %d exchanges, want 1", len(converted.Exchanges))
+	}
+	e := converted.Exchanges[0]
+	if string(e.Response.Body) != "Hello, world!" {
+		t.Errorf("got body %q, want the default variant", e.Response.Body)
+	}
+	if e.Response.Header.Get("Variants") != "" || e.Response.Header.Get("Variant-Key") != "" {
+		t.Errorf("Variants headers are not removed: %v", e.Response.Header)
+	}
+	if b.Exchanges[0].Response.Header.Get("Variants") == "" {
+		t.Error("ConvertTo modified the original exchange")
+	}
+}
+
+func TestConvertToB1WithoutPrimaryURL(t *testing.T) {
+	b := &Bundle{
+		Version:   version.VersionB2,
+		Exchanges: []*Exchange{createTestExchange("https://example.com/", "a")},
+	}
+	if _, _, err := b.ConvertTo(version.VersionB1); err == nil {
+		t.Error("ConvertTo unexpectedly succeeded")
+	}
+}
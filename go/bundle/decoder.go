@@ -46,6 +46,9 @@ type meta struct {
 	sectionsStart  uint64
 	manifestURL    *url.URL
 	signatures     *Signatures
+	dependencies   []*Dependency
+	critical       []string
+	unknown        []*Section
 	requests       []requestEntryWithOffset
 }
 
@@ -156,7 +159,7 @@ func parseIndexSection(sectionContents []byte, sectionsStart uint64, sos []secti
 	}
 	respSectionOffset := sectionsStart + respSectionRelOffset
 	makeRelativeToStream := func(offset, length uint64) (uint64, uint64, error) {
-		if offset+length > respso.Length {
+		if length > respso.Length || offset > respso.Length-length {
 			return 0, 0, errors.New("bundle.index: response length out-of-range")
 		}
 		return respSectionOffset + offset, length, nil
@@ -217,7 +220,7 @@ func parseIndexSectionWithVariants(sectionContents []byte, sectionsStart uint64,
 	}
 	respSectionOffset := sectionsStart + respSectionRelOffset
 	makeRelativeToStream := func(offset, length uint64) (uint64, uint64, error) {
-		if offset+length > respso.Length {
+		if length > respso.Length || offset > respso.Length-length {
 			return 0, 0, errors.New("bundle.index: response length out-of-range")
 		}
 		return respSectionOffset + offset, length, nil
@@ -416,12 +419,88 @@ func parseSignaturesSection(sectionContents []byte) (*Signatures, error) {
 	}, nil
 }
 
+// https://github.com/WICG/webpackage/blob/main/extensions/proposals/dependencies-section.md
+func parseDependenciesSection(sectionContents []byte) ([]*Dependency, error) {
+	// dependencies = {* whatwg-url => [web-bundle-url: whatwg-url, load-type]}
+	// load-type = "preload" / "lazy"
+	dec := cbor.NewDecoder(bytes.NewBuffer(sectionContents))
+	n, err := dec.DecodeMapHeader()
+	if err != nil {
+		return nil, fmt.Errorf("bundle.dependencies: failed to decode map header: %v", err)
+	}
+	deps := []*Dependency{}
+	seen := make(map[string]struct{})
+	for i := uint64(0); i < n; i++ {
+		rawResourceURL, err := dec.DecodeTextString()
+		if err != nil {
+			return nil, fmt.Errorf("bundle.dependencies[%d]: failed to decode resource URL: %v", i, err)
+		}
+		if _, exists := seen[rawResourceURL]; exists {
+			return nil, fmt.Errorf("bundle.dependencies[%d]: duplicated resource URL %q", i, rawResourceURL)
+		}
+		seen[rawResourceURL] = struct{}{}
+		resourceURL, err := url.Parse(rawResourceURL)
+		if err != nil {
+			return nil, fmt.Errorf("bundle.dependencies[%d]: failed to parse resource URL: %v", i, err)
+		}
+		numItems, err := dec.DecodeArrayHeader()
+		if err != nil {
+			return nil, fmt.Errorf("bundle.dependencies[%d]: failed to decode value array header: %v", i, err)
+		}
+		if numItems != 2 {
+			return nil, fmt.Errorf("bundle.dependencies[%d]: value array must be exactly 2 elements: bundle URL and load type.", i)
+		}
+		rawBundleURL, err := dec.DecodeTextString()
+		if err != nil {
+			return nil, fmt.Errorf("bundle.dependencies[%d]: failed to decode bundle URL: %v", i, err)
+		}
+		bundleURL, err := url.Parse(rawBundleURL)
+		if err != nil {
+			return nil, fmt.Errorf("bundle.dependencies[%d]: failed to parse bundle URL: %v", i, err)
+		}
+		loadType, err := dec.DecodeTextString()
+		if err != nil {
+			return nil, fmt.Errorf("bundle.dependencies[%d]: failed to decode load type: %v", i, err)
+		}
+		d := &Dependency{ResourceURL: resourceURL, BundleURL: bundleURL, LoadType: LoadType(loadType)}
+		if err := d.Validate(); err != nil {
+			return nil, err
+		}
+		deps = append(deps, d)
+	}
+	return deps, nil
+}
+
+// https://wpack-wg.github.io/bundled-responses/draft-ietf-wpack-bundled-responses.html#name-the-critical-section
+func parseCriticalSection(sectionContents []byte) ([]string, error) {
+	// critical = [*tstr]
+	dec := cbor.NewDecoder(bytes.NewBuffer(sectionContents))
+	n, err := dec.DecodeArrayHeader()
+	if err != nil {
+		return nil, fmt.Errorf("bundle.critical: failed to decode array header: %v", err)
+	}
+	critical := []string{}
+	for i := uint64(0); i < n; i++ {
+		name, err := dec.DecodeTextString()
+		if err != nil {
+			return nil, fmt.Errorf("bundle.critical[%d]: failed to decode section name: %v", i, err)
+		}
+		if _, exists := knownSections[name]; !exists {
+			return nil, fmt.Errorf("bundle: unknown section %q is marked as critical", name)
+		}
+		critical = append(critical, name)
+	}
+	return critical, nil
+}
+
 var knownSections = map[string]struct{}{
-	"index":      {},
-	"manifest":   {},
-	"primary":    {},
-	"signatures": {},
-	"responses":  {},
+	"critical":     {},
+	"dependencies": {},
+	"index":        {},
+	"manifest":     {},
+	"primary":      {},
+	"signatures":   {},
+	"responses":    {},
 }
 
 type MetadataErrorType int
@@ -437,13 +516,82 @@ type LoadMetadataError struct {
 	FallbackURL *url.URL
 }
 
+func (e *LoadMetadataError) Unwrap() error {
+	return e.error
+}
+
+// loadFallbackURL continues parsing a bundle of an unknown version from r,
+// positioned just after the version magic, to recover the URL a client should
+// load instead. It returns a VersionError with the URL, or a FormatError if
+// the fallback URL of a bundle with the b1 header layout is malformed.
+//
+// Bundles with the b1 header layout have the fallback URL right after the
+// version. Otherwise, the URL is taken from the "primary" section if the
+// section lengths can be parsed; if they cannot, the FallbackURL of the error
+// is nil.
+func loadFallbackURL(ra io.ReaderAt, r *io.SectionReader) error {
+	verErr := fmt.Errorf("bundle: unsupported version: %w", version.ErrUnknownVersion)
+
+	hdrMagic := make([]byte, len(version.HeaderMagicBytesB1))
+	if _, err := ra.ReadAt(hdrMagic, 0); err != nil {
+		return &LoadMetadataError{err, FormatError, nil}
+	}
+	dec := cbor.NewDecoder(r)
+	if bytes.Equal(hdrMagic, version.HeaderMagicBytesB1) {
+		fallbackURLBytes, err := dec.DecodeTextString()
+		if err != nil {
+			return &LoadMetadataError{fmt.Errorf("bundle: Failed to read fallbackURL string: %v", err), FormatError, nil}
+		}
+		fallbackURL, err := url.Parse(fallbackURLBytes)
+		if err != nil {
+			return &LoadMetadataError{fmt.Errorf("bundle: Failed to parse fallbackURL: %v", err), FormatError, nil}
+		}
+		return &LoadMetadataError{verErr, VersionError, fallbackURL}
+	}
+
+	slbytes, err := dec.DecodeByteString()
+	if err != nil || len(slbytes) >= 8192 {
+		return &LoadMetadataError{verErr, VersionError, nil}
+	}
+	sos, err := decodeSectionLengthsCBOR(slbytes)
+	if err != nil {
+		return &LoadMetadataError{verErr, VersionError, nil}
+	}
+	so, relOffset, found := FindSection(sos, "primary")
+	if !found || so.Length >= 8192 {
+		return &LoadMetadataError{verErr, VersionError, nil}
+	}
+	if _, err := dec.DecodeArrayHeader(); err != nil {
+		return &LoadMetadataError{verErr, VersionError, nil}
+	}
+	// The header is followed by the sections, the same way as in loadMetadata.
+	pos, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return &LoadMetadataError{verErr, VersionError, nil}
+	}
+	sectionContents := make([]byte, so.Length)
+	if _, err := ra.ReadAt(sectionContents, pos+int64(relOffset)); err != nil {
+		return &LoadMetadataError{verErr, VersionError, nil}
+	}
+	primaryURL, err := parsePrimarySection(sectionContents)
+	if err != nil {
+		return &LoadMetadataError{verErr, VersionError, nil}
+	}
+	return &LoadMetadataError{verErr, VersionError, primaryURL}
+}
+
 // https://wicg.github.io/webpackage/draft-yasskin-dispatch-bundled-exchanges.html#load-metadata
-func loadMetadata(bs []byte) (*meta, error) {
+func loadMetadata(ra io.ReaderAt, size int64, opts *ReadOptions) (*meta, error) {
+	if opts.MaxSize > 0 && size > opts.MaxSize {
+		return nil, &LoadMetadataError{fmt.Errorf("bundle: size %d exceeds the limit of %d bytes", size, opts.MaxSize), FormatError, nil}
+	}
 
-	r := bytes.NewBuffer(bs)
+	r := io.NewSectionReader(ra, 0, size)
 
 	ver, err := version.ParseMagicBytes(r)
-	// TODO(ksakamoto): Continue and return VersionError after parsing fallbackUrl.
+	if err == version.ErrUnknownVersion {
+		return nil, loadFallbackURL(ra, r)
+	}
 	if err != nil {
 		return nil, &LoadMetadataError{err, FormatError, nil}
 	}
@@ -466,7 +614,7 @@ func loadMetadata(bs []byte) (*meta, error) {
 		return nil, &LoadMetadataError{fmt.Errorf("bundle: Failed to read sectionLengths byte string: %v", err), FormatError, fallbackURL}
 	}
 	if len(slbytes) >= 8192 {
-		return nil, &LoadMetadataError{fmt.Errorf("bundle: sectionLengthsLength is too long (%d bytes)", slbytes), FormatError, fallbackURL}
+		return nil, &LoadMetadataError{fmt.Errorf("bundle: sectionLengthsLength is too long (%d bytes)", len(slbytes)), FormatError, fallbackURL}
 	}
 
 	sos, err := decodeSectionLengthsCBOR(slbytes)
@@ -482,7 +630,13 @@ func loadMetadata(bs []byte) (*meta, error) {
 		return nil, &LoadMetadataError{fmt.Errorf("bundle: Expected %d sections, got %d sections", len(sos), numSections), FormatError, fallbackURL}
 	}
 
-	sectionsStart := uint64(len(bs) - r.Len())
+	// The CBOR decoder reads exactly as many bytes as it consumes, so the
+	// current position of r is the start of the first section.
+	pos, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, &LoadMetadataError{err, FormatError, fallbackURL}
+	}
+	sectionsStart := uint64(pos)
 
 	if len(sos) == 0 || sos[len(sos)-1].Name != "responses" {
 		return nil, &LoadMetadataError{fmt.Errorf("bundle: Last section is not \"responses\""), FormatError, fallbackURL}
@@ -497,22 +651,33 @@ func loadMetadata(bs []byte) (*meta, error) {
 
 	offset := sectionsStart
 
+	// Unknown sections whose following known section is not known yet.
+	var unplaced []*Section
 	for _, so := range sos {
-		if _, exists := knownSections[so.Name]; !exists {
-			continue
+		if uint64(size) <= offset {
+			return nil, &LoadMetadataError{fmt.Errorf("bundle: section %q's computed offset %d out-of-range.", so.Name, offset), FormatError, fallbackURL}
+		}
+		end := offset + so.Length
+		if end < offset || uint64(size) < end {
+			return nil, &LoadMetadataError{fmt.Errorf("bundle: section %q's end %d out-of-range.", so.Name, end), FormatError, fallbackURL}
+		}
+		if _, known := knownSections[so.Name]; known {
+			for _, s := range unplaced {
+				// Being right before the responses section is the default.
+				if so.Name != "responses" {
+					s.Before = so.Name
+				}
+			}
+			unplaced = nil
 		}
 		if so.Name == "responses" {
 			continue
 		}
-		if uint64(len(bs)) <= offset {
-			return nil, &LoadMetadataError{fmt.Errorf("bundle: section %q's computed offset %q out-of-range.", so.Name, offset), FormatError, fallbackURL}
-		}
-		end := offset + so.Length
-		if uint64(len(bs)) <= end {
-			return nil, &LoadMetadataError{fmt.Errorf("bundle: section %q's end %q out-of-range.", so.Name, end), FormatError, fallbackURL}
-		}
 
-		sectionContents := bs[offset:end]
+		sectionContents := make([]byte, so.Length)
+		if _, err := ra.ReadAt(sectionContents, int64(offset)); err != nil {
+			return nil, &LoadMetadataError{fmt.Errorf("bundle: Failed to read section %q: %v", so.Name, err), FormatError, fallbackURL}
+		}
 
 		switch so.Name {
 		case "index":
@@ -552,23 +717,52 @@ func loadMetadata(bs []byte) (*meta, error) {
 			} else {
 				return nil, &LoadMetadataError{errors.New("bundle: signatures section not allowed in this version of bundle"), FormatError, fallbackURL}
 			}
-		case "responses":
-			continue
+		case "dependencies":
+			dependencies, err := parseDependenciesSection(sectionContents)
+			if err != nil {
+				return nil, &LoadMetadataError{err, FormatError, fallbackURL}
+			}
+			meta.dependencies = dependencies
+		case "critical":
+			critical, err := parseCriticalSection(sectionContents)
+			if err != nil {
+				return nil, &LoadMetadataError{err, FormatError, fallbackURL}
+			}
+			meta.critical = critical
 		default:
-			return nil, &LoadMetadataError{fmt.Errorf("bundle: unknown section: %q", so.Name), FormatError, fallbackURL}
+			s := &Section{Name: so.Name, Contents: sectionContents}
+			meta.unknown = append(meta.unknown, s)
+			unplaced = append(unplaced, s)
 		}
 
 		offset = end
 	}
 
+	if opts.MaxExchanges > 0 && len(meta.requests) > opts.MaxExchanges {
+		return nil, &LoadMetadataError{fmt.Errorf("bundle: number of exchanges %d exceeds the limit of %d", len(meta.requests), opts.MaxExchanges), FormatError, fallbackURL}
+	}
 	return meta, nil
 }
 
 var reStatus = regexp.MustCompile("^\\d\\d\\d$")
 
 // https://wicg.github.io/webpackage/draft-yasskin-dispatch-bundled-exchanges.html#load-response
-func loadResponse(req requestEntryWithOffset, bs []byte) (Response, error) {
-	r := bytes.NewBuffer(bs[req.Offset : req.Offset+req.Length])
+func loadResponse(req requestEntryWithOffset, ra io.ReaderAt, opts *ReadOptions) (Response, error) {
+	if opts.MaxHeaderBytes > 0 && opts.MaxBodySize > 0 {
+		// The array header and the two byte string headers take at most 19
+		// bytes.
+		if max := uint64(opts.MaxHeaderBytes) + uint64(opts.MaxBodySize) + 19; req.Length > max {
+			return Response{}, fmt.Errorf("bundle: encoded response length %d exceeds the limit of %d bytes", req.Length, max)
+		}
+	}
+	if opts.MaxSize > 0 && req.Length > uint64(opts.MaxSize) {
+		return Response{}, fmt.Errorf("bundle: encoded response length %d exceeds the limit of %d bytes", req.Length, opts.MaxSize)
+	}
+	bs := make([]byte, req.Length)
+	if _, err := ra.ReadAt(bs, int64(req.Offset)); err != nil {
+		return Response{}, fmt.Errorf("bundle: Failed to read the encoded response: %v", err)
+	}
+	r := bytes.NewBuffer(bs)
 
 	b, err := r.ReadByte()
 	if err != nil {
@@ -583,6 +777,9 @@ func loadResponse(req requestEntryWithOffset, bs []byte) (Response, error) {
 	if err != nil {
 		return Response{}, fmt.Errorf("bundle: Failed to decode response header cbor bytestring: %v", err)
 	}
+	if opts.MaxHeaderBytes > 0 && len(headerCborBytes) > opts.MaxHeaderBytes {
+		return Response{}, fmt.Errorf("bundle.response: headers of %d bytes exceed the limit of %d bytes", len(headerCborBytes), opts.MaxHeaderBytes)
+	}
 
 	rhdr := bytes.NewBuffer(headerCborBytes)
 	dechdr := cbor.NewDecoder(rhdr)
@@ -590,6 +787,9 @@ func loadResponse(req requestEntryWithOffset, bs []byte) (Response, error) {
 	if err != nil {
 		return Response{}, fmt.Errorf("bundle.response headerCbor: %v", err)
 	}
+	if opts.MaxHeaders > 0 && len(headers) > opts.MaxHeaders {
+		return Response{}, fmt.Errorf("bundle.response headerCbor: %d headers exceed the limit of %d", len(headers), opts.MaxHeaders)
+	}
 
 	status, exists := pseudos[":status"]
 	if !exists {
@@ -607,6 +807,9 @@ func loadResponse(req requestEntryWithOffset, bs []byte) (Response, error) {
 	if err != nil {
 		return Response{}, fmt.Errorf("bundle.response.body: %v", err)
 	}
+	if opts.MaxBodySize > 0 && int64(len(body)) > opts.MaxBodySize {
+		return Response{}, fmt.Errorf("bundle.response.body: length %d exceeds the limit of %d bytes", len(body), opts.MaxBodySize)
+	}
 
 	if r.Len() != 0 {
 		return Response{}, fmt.Errorf("bundle.response: invalid request stream end")
@@ -614,7 +817,7 @@ func loadResponse(req requestEntryWithOffset, bs []byte) (Response, error) {
 
 	nstatus, err := strconv.Atoi(status)
 	if err != nil {
-		panic(err)
+		return Response{}, fmt.Errorf("bundle.response headerCbor: pseudos['status'] %q invalid: %v", status, err)
 	}
 
 	res := Response{
@@ -626,33 +829,25 @@ func loadResponse(req requestEntryWithOffset, bs []byte) (Response, error) {
 	return res, nil
 }
 
+// Read reads a whole bundle from r and decodes all of its exchanges.
 func Read(r io.Reader) (*Bundle, error) {
+	return ReadWithOptions(r, ReadOptions{})
+}
+
+// ReadWithOptions is like Read, but fails if the bundle exceeds the limits of
+// opts. With opts.MaxSize set, at most opts.MaxSize+1 bytes are read from r.
+func ReadWithOptions(r io.Reader, opts ReadOptions) (*Bundle, error) {
+	if opts.MaxSize > 0 {
+		r = io.LimitReader(r, opts.MaxSize+1)
+	}
 	bs, err := ioutil.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
 
-	m, err := loadMetadata(bs)
+	br, err := OpenWithOptions(bytes.NewReader(bs), int64(len(bs)), opts)
 	if err != nil {
 		return nil, err
 	}
-
-	// log.Printf("meta: %+v", m)
-
-	es := []*Exchange{}
-	for _, req := range m.requests {
-		res, err := loadResponse(req, bs)
-		if err != nil {
-			return nil, err
-		}
-
-		e := &Exchange{
-			Request:  req.Request,
-			Response: res,
-		}
-		es = append(es, e)
-	}
-
-	b := &Bundle{Version: m.version, PrimaryURL: m.primaryURL, Exchanges: es, ManifestURL: m.manifestURL, Signatures: m.signatures}
-	return b, nil
+	return br.ReadAll()
 }
@@ -0,0 +1,187 @@
+package bundle
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/WICG/webpackage/go/bundle/version"
+	"github.com/WICG/webpackage/go/internal/cbor"
+)
+
+func createFuzzSeedBundles() []*Bundle {
+	var bs []*Bundle
+	for _, ver := range version.AllVersions {
+		b := &Bundle{
+			Version:    ver,
+			PrimaryURL: urlMustParse("https://example.com/"),
+			Exchanges: []*Exchange{
+				&Exchange{
+					Request{URL: urlMustParse("https://example.com/")},
+					Response{
+						Status: 200,
+						Header: http.Header{"Content-Type": []string{"text/html"}},
+						Body:   []byte("hello, world!"),
+					},
+				},
+				&Exchange{
+					Request{URL: urlMustParse("style.css")},
+					Response{
+						Status: 404,
+						Header: http.Header{"Content-Type": []string{"text/plain"}},
+					},
+				},
+			},
+			Signatures: &Signatures{
+				VouchedSubsets: []*VouchedSubset{
+					&VouchedSubset{Authority: 0, Sig: []byte("sig"), Signed: []byte("signed")},
+				},
+			},
+			Dependencies: []*Dependency{
+				&Dependency{urlMustParse("a.js"), urlMustParse("a.wbn"), LoadTypeLazy},
+			},
+			Critical:        []string{"dependencies"},
+			UnknownSections: []*Section{&Section{Name: "x-unknown", Contents: []byte{0x60}}},
+		}
+		if ver.SupportsManifestSection() {
+			b.ManifestURL = urlMustParse("https://example.com/manifest.json")
+		}
+		bs = append(bs, b)
+	}
+	return bs
+}
+
+func encodeFuzzSeed(t testing.TB, b *Bundle) []byte {
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// encodeBundleWithIndexEntry encodes a bundle of version ver whose index has
+// an entry for https://example.com/ with the given offset and length.
+func encodeBundleWithIndexEntry(t testing.TB, ver version.Version, offset, length uint64) []byte {
+	var index bytes.Buffer
+	enc := cbor.NewEncoder(&index)
+	err := enc.EncodeMap([]*cbor.MapEntryEncoder{
+		cbor.GenerateMapEntry(func(keyE *cbor.Encoder, valueE *cbor.Encoder) {
+			keyE.EncodeTextString("https://example.com/")
+			if ver.SupportsVariants() {
+				valueE.EncodeArrayHeader(3)
+				valueE.EncodeByteString(nil) // No variants.
+			} else {
+				valueE.EncodeArrayHeader(2)
+			}
+			valueE.EncodeUint(offset)
+			valueE.EncodeUint(length)
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Bundle{Version: ver, PrimaryURL: urlMustParse("https://example.com/")}
+	sections := []section{
+		&rawSection{Reader: bytes.NewReader(index.Bytes()), name: "index"},
+		&rawSection{Reader: bytes.NewReader([]byte{0x81, 0x82, 0x40, 0x40}), name: "responses"},
+	}
+	var buf bytes.Buffer
+	if err := b.writeHeader(&buf, sections); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range sections {
+		if _, err := s.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writeFooter(&buf, buf.Len()); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadOverflowingIndexEntry(t *testing.T) {
+	for _, ver := range version.AllVersions {
+		// offset+length wraps around to 0.
+		data := encodeBundleWithIndexEntry(t, ver, 1, math.MaxUint64)
+		if _, err := Read(bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: Read unexpectedly succeeded", ver)
+		}
+		if _, err := ReadWithOptions(bytes.NewReader(data), *fuzzReadOptions); err == nil {
+			t.Errorf("%s: ReadWithOptions unexpectedly succeeded", ver)
+		}
+	}
+}
+
+var fuzzReadOptions = &ReadOptions{
+	MaxSize:        1 << 20,
+	MaxExchanges:   100,
+	MaxHeaders:     100,
+	MaxHeaderBytes: 1 << 16,
+	MaxBodySize:    1 << 20,
+}
+
+// fuzzReadOptionSets are the options each input is decoded with: with limits,
+// and without any, as Read does.
+var fuzzReadOptionSets = []*ReadOptions{fuzzReadOptions, &ReadOptions{}}
+
+func FuzzLoadMetadata(f *testing.F) {
+	for _, b := range createFuzzSeedBundles() {
+		f.Add(encodeFuzzSeed(f, b))
+		f.Add(encodeBundleWithIndexEntry(f, b.Version, 1, math.MaxUint64))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, opts := range fuzzReadOptionSets {
+			m, err := loadMetadata(bytes.NewReader(data), int64(len(data)), opts)
+			if err != nil {
+				continue
+			}
+			for _, req := range m.requests {
+				loadResponse(req, bytes.NewReader(data), opts)
+			}
+		}
+	})
+}
+
+func FuzzLoadResponse(f *testing.F) {
+	for _, b := range createFuzzSeedBundles() {
+		data := encodeFuzzSeed(f, b)
+		m, err := loadMetadata(bytes.NewReader(data), int64(len(data)), &ReadOptions{})
+		if err != nil {
+			f.Fatal(err)
+		}
+		for _, req := range m.requests {
+			f.Add(data[req.Offset : req.Offset+req.Length])
+		}
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		req := requestEntryWithOffset{Length: uint64(len(data))}
+		for _, opts := range fuzzReadOptionSets {
+			loadResponse(req, bytes.NewReader(data), opts)
+		}
+	})
+}
+
+func FuzzParseSignaturesSection(f *testing.F) {
+	for _, b := range createFuzzSeedBundles() {
+		sections, err := b.buildSections()
+		if err != nil {
+			f.Fatal(err)
+		}
+		for _, s := range sections {
+			if s.Name() != "signatures" {
+				continue
+			}
+			var buf bytes.Buffer
+			if _, err := s.WriteTo(&buf); err != nil {
+				f.Fatal(err)
+			}
+			f.Add(buf.Bytes())
+		}
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		parseSignaturesSection(data)
+	})
+}
@@ -0,0 +1,205 @@
+package bundle
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/WICG/webpackage/go/signedexchange/certurl"
+)
+
+// Change is a value that differs between two bundles. An empty string
+// stands for a missing value.
+type Change struct {
+	Old string
+	New string
+}
+
+// HeaderChange is a response header whose values differ between two
+// exchanges. A nil slice stands for a missing header.
+type HeaderChange struct {
+	Name string
+	Old  []string
+	New  []string
+}
+
+// BodyChange describes a response body that differs between two exchanges.
+type BodyChange struct {
+	OldLength int
+	NewLength int
+}
+
+// ExchangeDiff describes the differences between the responses of two
+// exchanges for the same URL.
+type ExchangeDiff struct {
+	// URL is the request URL, followed by the Variant-Key of the response if
+	// it has one.
+	URL     string
+	Status  *Change         `json:",omitempty"`
+	Headers []*HeaderChange `json:",omitempty"`
+	Body    *BodyChange     `json:",omitempty"`
+	// Old and New are the compared exchanges.
+	Old *Exchange `json:"-"`
+	New *Exchange `json:"-"`
+}
+
+// BundleDiff describes the differences between two bundles. Exchanges are
+// matched by their URL (and Variant-Key), and signature authorities by the
+// SHA-256 fingerprint of their certificate.
+type BundleDiff struct {
+	Version            *Change         `json:",omitempty"`
+	PrimaryURL         *Change         `json:",omitempty"`
+	ManifestURL        *Change         `json:",omitempty"`
+	AddedAuthorities   []string        `json:",omitempty"`
+	RemovedAuthorities []string        `json:",omitempty"`
+	AddedExchanges     []string        `json:",omitempty"`
+	RemovedExchanges   []string        `json:",omitempty"`
+	ChangedExchanges   []*ExchangeDiff `json:",omitempty"`
+}
+
+// Empty reports whether d has no differences.
+func (d *BundleDiff) Empty() bool {
+	return reflect.DeepEqual(d, &BundleDiff{})
+}
+
+func diffString(old, new string) *Change {
+	if old == new {
+		return nil
+	}
+	return &Change{old, new}
+}
+
+func urlString(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	return u.String()
+}
+
+// authorityName identifies a signature authority in a BundleDiff.
+func authorityName(ac *certurl.AugmentedCertificate) string {
+	return fmt.Sprintf("%s (sha256:%x)", ac.Cert.Subject.CommonName, sha256.Sum256(ac.Cert.Raw))
+}
+
+func authorityNames(b *Bundle) []string {
+	if b.Signatures == nil {
+		return nil
+	}
+	var names []string
+	for _, ac := range b.Signatures.Authorities {
+		names = append(names, authorityName(ac))
+	}
+	return names
+}
+
+// subtract returns the elements of xs that are not in ys, in order.
+func subtract(xs, ys []string) []string {
+	m := make(map[string]struct{})
+	for _, y := range ys {
+		m[y] = struct{}{}
+	}
+	var ret []string
+	for _, x := range xs {
+		if _, ok := m[x]; !ok {
+			ret = append(ret, x)
+		}
+	}
+	return ret
+}
+
+func diffHeaders(a, b http.Header) []*HeaderChange {
+	names := make(map[string]struct{})
+	for name := range a {
+		names[http.CanonicalHeaderKey(name)] = struct{}{}
+	}
+	for name := range b {
+		names[http.CanonicalHeaderKey(name)] = struct{}{}
+	}
+	var changes []*HeaderChange
+	for name := range names {
+		old, new := a.Values(name), b.Values(name)
+		if !reflect.DeepEqual(old, new) {
+			changes = append(changes, &HeaderChange{name, old, new})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+func diffExchange(key string, a, b *Exchange) (*ExchangeDiff, error) {
+	d := &ExchangeDiff{URL: key, Old: a, New: b}
+	d.Status = diffString(strconv.Itoa(a.Response.Status), strconv.Itoa(b.Response.Status))
+	d.Headers = diffHeaders(a.Response.Header, b.Response.Header)
+	aBody, err := a.Response.BodyBytes()
+	if err != nil {
+		return nil, fmt.Errorf("bundle: %s: %v", key, err)
+	}
+	bBody, err := b.Response.BodyBytes()
+	if err != nil {
+		return nil, fmt.Errorf("bundle: %s: %v", key, err)
+	}
+	if string(aBody) != string(bBody) {
+		d.Body = &BodyChange{
+			OldLength: len(aBody),
+			NewLength: len(bBody),
+		}
+	}
+	if d.Status == nil && d.Headers == nil && d.Body == nil {
+		return nil, nil
+	}
+	return d, nil
+}
+
+// Diff compares the bundles a and b, treating a as the old one. It fails if
+// a body set via Response.BodySource cannot be read.
+func Diff(a, b *Bundle) (*BundleDiff, error) {
+	d := &BundleDiff{
+		Version:     diffString(string(a.Version), string(b.Version)),
+		PrimaryURL:  diffString(urlString(a.PrimaryURL), urlString(b.PrimaryURL)),
+		ManifestURL: diffString(urlString(a.ManifestURL), urlString(b.ManifestURL)),
+	}
+
+	aAuths, bAuths := authorityNames(a), authorityNames(b)
+	d.AddedAuthorities = subtract(bAuths, aAuths)
+	d.RemovedAuthorities = subtract(aAuths, bAuths)
+
+	// If a bundle has multiple exchanges for a key, the first one is
+	// compared, as it is the one a browser would load.
+	aExchanges := make(map[string]*Exchange)
+	for _, e := range a.Exchanges {
+		if key := exchangeKey(e); aExchanges[key] == nil {
+			aExchanges[key] = e
+		}
+	}
+	bExchanges := make(map[string]*Exchange)
+	for _, e := range b.Exchanges {
+		key := exchangeKey(e)
+		if bExchanges[key] != nil {
+			continue
+		}
+		bExchanges[key] = e
+		ae, ok := aExchanges[key]
+		if !ok {
+			d.AddedExchanges = append(d.AddedExchanges, key)
+			continue
+		}
+		ed, err := diffExchange(key, ae, e)
+		if err != nil {
+			return nil, err
+		}
+		if ed != nil {
+			d.ChangedExchanges = append(d.ChangedExchanges, ed)
+		}
+	}
+	for _, e := range a.Exchanges {
+		key := exchangeKey(e)
+		if _, ok := bExchanges[key]; !ok && aExchanges[key] == e {
+			d.RemovedExchanges = append(d.RemovedExchanges, key)
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,92 @@
+package bundle_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	. "github.com/WICG/webpackage/go/bundle"
+	"github.com/WICG/webpackage/go/bundle/version"
+)
+
+func TestDiff(t *testing.T) {
+	a := &Bundle{
+		Version:    version.VersionB2,
+		PrimaryURL: urlMustParse("https://example.com/"),
+		Exchanges: []*Exchange{
+			createTestExchange("https://example.com/", "a\nb\nc\nd\ne\nf\ng\nh\n"),
+			createTestExchange("https://example.com/removed", "removed"),
+			createTestExchange("https://example.com/same", "same"),
+			createTestExchange("https://example.com/image", "\x00\x01"),
+		},
+	}
+	b := &Bundle{
+		Version:     version.VersionB2,
+		PrimaryURL:  urlMustParse("https://example.com/"),
+		ManifestURL: urlMustParse("https://example.com/manifest.json"),
+		Exchanges: []*Exchange{
+			createTestExchange("https://example.com/", "a\nb\nc\nd\nE\nf\ng\nh\n"),
+			createTestExchange("https://example.com/same", "same"),
+			createTestExchange("https://example.com/added", "added"),
+			createTestExchange("https://example.com/image", "\x00\x02"),
+		},
+	}
+	b.Exchanges[3].Response.Header = http.Header{"Content-Type": []string{"image/png"}}
+	b.Exchanges[3].Response.Status = 404
+
+	got, err := Diff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &BundleDiff{
+		ManifestURL:      &Change{"", "https://example.com/manifest.json"},
+		AddedExchanges:   []string{"https://example.com/added"},
+		RemovedExchanges: []string{"https://example.com/removed"},
+		ChangedExchanges: []*ExchangeDiff{
+			{
+				URL:  "https://example.com/",
+				Body: &BodyChange{OldLength: 16, NewLength: 16},
+				Old:  a.Exchanges[0],
+				New:  b.Exchanges[0],
+			},
+			{
+				URL:    "https://example.com/image",
+				Status: &Change{"200", "404"},
+				Headers: []*HeaderChange{
+					{"Content-Type", []string{"text/plain"}, []string{"image/png"}},
+				},
+				Body: &BodyChange{OldLength: 2, NewLength: 2},
+				Old:  a.Exchanges[3],
+				New:  b.Exchanges[3],
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+		for i, e := range got.ChangedExchanges {
+			t.Logf("ChangedExchanges[%d]: %+v body: %+v", i, e, e.Body)
+		}
+	}
+
+	if d, err := Diff(a, a); err != nil || !d.Empty() {
+		t.Errorf("Diff of the same bundle: got %+v, %v", d, err)
+	}
+}
+
+func TestDiffBodySource(t *testing.T) {
+	a := &Bundle{Version: version.VersionB2, Exchanges: []*Exchange{createTestExchange("https://example.com/", "same")}}
+	b := &Bundle{Version: version.VersionB2, Exchanges: []*Exchange{createTestExchange("https://example.com/", "")}}
+	b.Exchanges[0].Response.BodySource = BytesBody([]byte("same"))
+	if d, err := Diff(a, b); err != nil || !d.Empty() {
+		t.Errorf("got %+v, %v, want an empty diff", d, err)
+	}
+
+	b.Exchanges[0].Response.BodySource = BytesBody([]byte("changed"))
+	d, err := Diff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.ChangedExchanges) != 1 || d.ChangedExchanges[0].Body == nil || d.ChangedExchanges[0].Body.NewLength != len("changed") {
+		t.Errorf("unexpected diff: %+v", d)
+	}
+}
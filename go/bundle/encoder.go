@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -375,49 +376,92 @@ func (is *indexSection) WriteTo(w io.Writer) (int64, error) {
 	return int64(n), err
 }
 
-// staging area for writing responses section
+// staging area for writing responses section. Response bodies are not
+// copied here; they are streamed from their BodySource in WriteTo.
 type responsesSection struct {
-	buf bytes.Buffer
+	head    []byte // CBOR array header
+	entries []*responseEntry
+	length  int
 }
 
-func newResponsesSection(n int) *responsesSection {
-	ret := &responsesSection{}
+type responseEntry struct {
+	prefix []byte // CBOR array header, header CBOR, and body byte string header
+	body   BodySource
+}
 
-	enc := cbor.NewEncoder(&ret.buf)
+func newResponsesSection(n int) *responsesSection {
+	var b bytes.Buffer
+	enc := cbor.NewEncoder(&b)
 	if err := enc.EncodeArrayHeader(n); err != nil {
 		panic(err)
 	}
 
-	return ret
+	return &responsesSection{head: b.Bytes(), length: b.Len()}
 }
 
 func (rs *responsesSection) addResponse(r Response) (int, int, error) {
-	offset := rs.buf.Len()
+	offset := rs.length
 
 	headerCbor, err := r.EncodeHeader()
 	if err != nil {
 		return 0, 0, err
 	}
 
-	enc := cbor.NewEncoder(&rs.buf)
+	body := r.BodySource
+	if body == nil {
+		body = BytesBody(r.Body)
+	}
+
+	var b bytes.Buffer
+	enc := cbor.NewEncoder(&b)
 	if err := enc.EncodeArrayHeader(2); err != nil {
 		return 0, 0, fmt.Errorf("bundle: failed to encode response array header: %v", err)
 	}
 	if err := enc.EncodeByteString(headerCbor); err != nil {
 		return 0, 0, fmt.Errorf("bundle: failed to encode response header cbor bytestring: %v", err)
 	}
-	if err := enc.EncodeByteString(r.Body); err != nil {
+	if err := enc.EncodeByteStringHeader(uint64(body.Len())); err != nil {
 		return 0, 0, fmt.Errorf("bundle: failed to encode response payload bytestring: %v", err)
 	}
 
-	length := rs.buf.Len() - offset
+	rs.entries = append(rs.entries, &responseEntry{prefix: b.Bytes(), body: body})
+	length := b.Len() + int(body.Len())
+	rs.length += length
 	return offset, length, nil
 }
 
 func (rs *responsesSection) Name() string { return "responses" }
-func (rs *responsesSection) Len() int     { return rs.buf.Len() }
+func (rs *responsesSection) Len() int     { return rs.length }
 func (rs *responsesSection) WriteTo(w io.Writer) (int64, error) {
-	return rs.buf.WriteTo(w)
+	cw := NewCountingWriter(w)
+	if _, err := cw.Write(rs.head); err != nil {
+		return cw.Written, err
+	}
+	for _, e := range rs.entries {
+		if _, err := cw.Write(e.prefix); err != nil {
+			return cw.Written, err
+		}
+		if err := copyBody(cw, e.body); err != nil {
+			return cw.Written, err
+		}
+	}
+	return cw.Written, nil
+}
+
+// copyBody writes exactly body.Len() bytes of body to w.
+func copyBody(w io.Writer, body BodySource) error {
+	r, err := body.Open()
+	if err != nil {
+		return fmt.Errorf("bundle: failed to open response body: %v", err)
+	}
+	defer r.Close()
+	if _, err := io.CopyN(w, r, body.Len()); err != nil {
+		if err == io.EOF {
+			return fmt.Errorf("bundle: response body is shorter than %d bytes", body.Len())
+		}
+		return fmt.Errorf("bundle: failed to copy response body: %v", err)
+	}
+	return nil
 }
 
 type primarySection struct {
@@ -493,6 +537,70 @@ func newSignaturesSection(sigs *Signatures) (*signaturesSection, error) {
 	return &ss, nil
 }
 
+type dependenciesSection struct {
+	bytes.Buffer
+}
+
+func (ds *dependenciesSection) Name() string { return "dependencies" }
+
+func newDependenciesSection(deps []*Dependency) (*dependenciesSection, error) {
+	var ds dependenciesSection
+	enc := cbor.NewEncoder(&ds)
+
+	mes := []*cbor.MapEntryEncoder{}
+	for _, d := range deps {
+		if err := d.Validate(); err != nil {
+			return nil, err
+		}
+		d := d
+		mes = append(mes, cbor.GenerateMapEntry(func(keyE *cbor.Encoder, valueE *cbor.Encoder) {
+			keyE.EncodeTextString(d.ResourceURL.String())
+			valueE.EncodeArrayHeader(2)
+			valueE.EncodeTextString(d.BundleURL.String())
+			valueE.EncodeTextString(string(d.LoadType))
+		}))
+	}
+	if err := enc.EncodeMap(mes); err != nil {
+		return nil, fmt.Errorf("bundle: Failed to encode dependencies section: %v", err)
+	}
+	return &ds, nil
+}
+
+type criticalSection struct {
+	bytes.Buffer
+}
+
+func (cs *criticalSection) Name() string { return "critical" }
+
+func newCriticalSection(names []string) (*criticalSection, error) {
+	var cs criticalSection
+	enc := cbor.NewEncoder(&cs)
+	if err := enc.EncodeArrayHeader(len(names)); err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if err := enc.EncodeTextString(name); err != nil {
+			return nil, err
+		}
+	}
+	return &cs, nil
+}
+
+// rawSection is a section whose contents are written as-is.
+type rawSection struct {
+	*bytes.Reader
+	name string
+}
+
+func (rs *rawSection) Name() string { return rs.name }
+
+func newRawSection(s *Section) (*rawSection, error) {
+	if _, known := knownSections[s.Name]; known {
+		return nil, fmt.Errorf("bundle: section %q is not an unknown section", s.Name)
+	}
+	return &rawSection{Reader: bytes.NewReader(s.Contents), name: s.Name}, nil
+}
+
 func addExchange(is *indexSection, rs *responsesSection, e *Exchange) error {
 	offset, length, err := rs.addResponse(e.Response)
 	if err != nil {
@@ -539,9 +647,10 @@ func writeSectionHeader(w io.Writer, numSections int) error {
 	return enc.EncodeArrayHeader(numSections)
 }
 
-func writeFooter(w io.Writer, offset int) error {
-	const footerLength = 9
+// footerLength is the length of the CBOR-encoded 8-byte bundle length.
+const footerLength = 9
 
+func writeFooter(w io.Writer, offset int) error {
 	bundleSize := uint64(offset) + footerLength
 
 	var b bytes.Buffer
@@ -559,19 +668,19 @@ func writeFooter(w io.Writer, offset int) error {
 	return nil
 }
 
-func (b *Bundle) WriteTo(w io.Writer) (int64, error) {
-	cw := NewCountingWriter(w)
-
+// buildSections prepares the sections of b in the order they are written.
+// Response bodies are not read until the sections are written.
+func (b *Bundle) buildSections() ([]section, error) {
 	is := &indexSection{}
 	rs := newResponsesSection(len(b.Exchanges))
 
 	for _, e := range b.Exchanges {
 		if err := addExchange(is, rs, e); err != nil {
-			return cw.Written, err
+			return nil, err
 		}
 	}
 	if err := is.Finalize(b.Version); err != nil {
-		return cw.Written, err
+		return nil, err
 	}
 
 	sections := []section{}
@@ -579,41 +688,109 @@ func (b *Bundle) WriteTo(w io.Writer) (int64, error) {
 	if !b.Version.HasPrimaryURLFieldInHeader() && b.PrimaryURL != nil {
 		ps, err := newPrimarySection(b.PrimaryURL)
 		if err != nil {
-			return cw.Written, err
+			return nil, err
 		}
 		sections = append(sections, ps)
 	}
 	if b.ManifestURL != nil {
 		if !b.Version.SupportsManifestSection() {
-			return cw.Written, errors.New("This version of the WebBundle does not support storing manifest URL.")
+			return nil, errors.New("This version of the WebBundle does not support storing manifest URL.")
 		}
 		ms, err := newManifestSection(b.ManifestURL)
 		if err != nil {
-			return cw.Written, err
+			return nil, err
 		}
 		sections = append(sections, ms)
 	}
 	if b.Signatures != nil && b.Version.SupportsSignatures() {
 		ss, err := newSignaturesSection(b.Signatures)
 		if err != nil {
-			return cw.Written, err
+			return nil, err
 		}
 		sections = append(sections, ss)
 	}
+	if len(b.Dependencies) > 0 {
+		ds, err := newDependenciesSection(b.Dependencies)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, ds)
+	}
+	if len(b.Critical) > 0 {
+		cs, err := newCriticalSection(b.Critical)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, cs)
+	}
 	sections = append(sections, rs) // resources section must be the last.
 
-	if _, err := cw.Write(b.Version.HeaderMagicBytes()); err != nil {
-		return cw.Written, err
+	// Put each unknown section right before the known section it preceded
+	// when read, or else before the responses section.
+	for _, us := range b.UnknownSections {
+		s, err := newRawSection(us)
+		if err != nil {
+			return nil, err
+		}
+		i := len(sections) - 1
+		for j, known := range sections {
+			if us.Before != "" && known.Name() == us.Before {
+				i = j
+				break
+			}
+		}
+		sections = append(sections[:i], append([]section{s}, sections[i:]...)...)
+	}
+	return sections, nil
+}
+
+// writeHeader writes everything that precedes the sections: the magic
+// bytes, the fallback URL, the section lengths and the sections array header.
+func (b *Bundle) writeHeader(w io.Writer, sections []section) error {
+	magic, err := b.Version.HeaderMagicBytes()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(magic); err != nil {
+		return err
 	}
 	if b.Version.HasPrimaryURLFieldInHeader() {
-		if err := writePrimaryURL(cw, b.PrimaryURL); err != nil {
-			return cw.Written, err
+		if err := writePrimaryURL(w, b.PrimaryURL); err != nil {
+			return err
 		}
 	}
-	if err := writeSectionOffsets(cw, sections); err != nil {
+	if err := writeSectionOffsets(w, sections); err != nil {
+		return err
+	}
+	return writeSectionHeader(w, len(sections))
+}
+
+// EncodedLen returns the number of bytes WriteTo would write for b, without
+// reading any response bodies.
+func (b *Bundle) EncodedLen() (int64, error) {
+	sections, err := b.buildSections()
+	if err != nil {
+		return 0, err
+	}
+	cw := NewCountingWriter(ioutil.Discard)
+	if err := b.writeHeader(cw, sections); err != nil {
+		return 0, err
+	}
+	n := cw.Written
+	for _, s := range sections {
+		n += int64(s.Len())
+	}
+	return n + footerLength, nil
+}
+
+func (b *Bundle) WriteTo(w io.Writer) (int64, error) {
+	cw := NewCountingWriter(w)
+
+	sections, err := b.buildSections()
+	if err != nil {
 		return cw.Written, err
 	}
-	if err := writeSectionHeader(cw, len(sections)); err != nil {
+	if err := b.writeHeader(cw, sections); err != nil {
 		return cw.Written, err
 	}
 	for _, s := range sections {
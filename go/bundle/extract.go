@@ -0,0 +1,155 @@
+package bundle
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ExtractSpecFile is the name of the file ExtractTo writes to the root of the
+// output directory to record the status and headers of each exchange.
+const ExtractSpecFile = "bundle.json"
+
+// extractSpec mirrors the JSON input of gen-bundle's -spec flag, so that the
+// output of ExtractTo can be turned back into a bundle with
+// `gen-bundle -spec dir/bundle.json`.
+type extractSpec struct {
+	Version     string                 `json:"version"`
+	PrimaryURL  string                 `json:"primaryURL,omitempty"`
+	ManifestURL string                 `json:"manifestURL,omitempty"`
+	Exchanges   []*extractExchangeSpec `json:"exchanges"`
+}
+
+type extractExchangeSpec struct {
+	URL     string            `json:"url"`
+	Status  int               `json:"status"`
+	File    string            `json:"file,omitempty"`
+	Headers map[string]string `json:"headers"`
+}
+
+// ExtractTo writes the response bodies of the bundle's exchanges to files
+// under dir. Bodies of absolute URLs are placed in a directory per origin
+// named "<scheme>_<host>" (e.g. "https_example.com"), and bodies of relative
+// URLs in a directory named "relative". Within it, the file path is the URL
+// path; a path ending in "/" gets "index.html" appended, and a query is kept
+// in the file name with its "?" escaped as "%3F".
+//
+// The URL, status and headers of every exchange are written to
+// ExtractSpecFile in dir, in the format of gen-bundle's -spec flag, so
+// `gen-bundle -spec dir/bundle.json` recreates the exchanges. A missing
+// Content-Type header is written with an empty value. Exchanges with a
+// non-200 status and an empty body, such as redirects, only appear there.
+// Running gen-bundle -dir on an origin directory instead generates the
+// headers from the files, and does not restore URLs with a query.
+//
+// ExtractTo fails if two exchanges map to the same file, which happens e.g.
+// for the variants of a resource.
+func (b *Bundle) ExtractTo(dir string) error {
+	spec := &extractSpec{Version: string(b.Version)}
+	if b.PrimaryURL != nil {
+		spec.PrimaryURL = b.PrimaryURL.String()
+	}
+	if b.ManifestURL != nil {
+		spec.ManifestURL = b.ManifestURL.String()
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("bundle: failed to create directory: %v", err)
+	}
+	files := make(map[string]*url.URL)
+	for _, e := range b.Exchanges {
+		s := &extractExchangeSpec{
+			URL:     e.Request.URL.String(),
+			Status:  e.Response.Status,
+			Headers: flattenHeader(e.Response.Header),
+		}
+		if hasBody(&e.Response) || e.Response.Status == http.StatusOK {
+			file := extractPath(e.Request.URL)
+			if u, ok := files[file]; ok {
+				return fmt.Errorf("bundle: %v and %v are both extracted to %q", u, e.Request.URL, file)
+			}
+			files[file] = e.Request.URL
+			if err := writeBodyFile(filepath.Join(dir, filepath.FromSlash(file)), &e.Response); err != nil {
+				return err
+			}
+			s.File = file
+		}
+		spec.Exchanges = append(spec.Exchanges, s)
+	}
+
+	data, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, ExtractSpecFile), append(data, '\n'), 0644)
+}
+
+// extractPath returns the slash-separated path, relative to the output
+// directory of ExtractTo, of the file that the body of u is written to.
+func extractPath(u *url.URL) string {
+	root := "relative"
+	if u.IsAbs() {
+		root = u.Scheme + "_" + strings.ReplaceAll(u.Host, ":", "_")
+	}
+	if u.Opaque != "" {
+		// e.g. urn:uuid:...
+		return root + "/" + url.PathEscape(u.Opaque)
+	}
+	p := u.Path
+	if p == "" || strings.HasSuffix(p, "/") {
+		p += "index.html"
+	}
+	// Cleaning the rooted path drops ".." segments, so the file cannot be
+	// outside of the root.
+	p = path.Clean("/" + p)
+	if u.RawQuery != "" {
+		p += "%3F" + strings.ReplaceAll(u.RawQuery, "/", "%2F")
+	}
+	return root + p
+}
+
+// flattenHeader joins the values of each header with ", ", which is how
+// the headers are encoded in a bundle anyway. A missing Content-Type is
+// recorded with an empty value, so that gen-bundle doesn't guess one from the
+// file extension.
+func flattenHeader(h http.Header) map[string]string {
+	m := map[string]string{"Content-Type": ""}
+	for name, values := range h {
+		m[name] = strings.Join(values, ", ")
+	}
+	return m
+}
+
+func hasBody(r *Response) bool {
+	if r.BodySource != nil {
+		return r.BodySource.Len() > 0
+	}
+	return len(r.Body) > 0
+}
+
+func writeBodyFile(file string, r *Response) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return fmt.Errorf("bundle: failed to create directory: %v", err)
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("bundle: failed to create %q: %v", file, err)
+	}
+	if r.BodySource != nil {
+		err = copyBody(f, r.BodySource)
+	} else {
+		_, err = f.Write(r.Body)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("bundle: failed to write %q: %v", file, err)
+	}
+	return nil
+}
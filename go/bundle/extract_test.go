@@ -0,0 +1,130 @@
+package bundle_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	. "github.com/WICG/webpackage/go/bundle"
+	"github.com/WICG/webpackage/go/bundle/version"
+)
+
+func TestExtractTo(t *testing.T) {
+	b := &Bundle{
+		Version:    version.VersionB2,
+		PrimaryURL: urlMustParse("https://example.com/"),
+		Exchanges: []*Exchange{
+			createTestExchange("https://example.com/", "index"),
+			createTestExchange("https://example.com/sub/a.txt", "a"),
+			createTestExchange("https://example.com/search?q=a/b", "search"),
+			createTestExchange("https://example.com:8443/../../escape.txt", "escape"),
+			createTestExchange("relative/b.txt", "b"),
+			&Exchange{
+				Request{URL: urlMustParse("https://example.com/old")},
+				Response{
+					Status: 301,
+					Header: http.Header{"Location": []string{"/"}, "Vary": []string{"A", "B"}},
+				},
+			},
+		},
+	}
+	b.Exchanges[1].Response.BodySource = BytesBody([]byte("a"))
+	b.Exchanges[1].Response.Body = nil
+
+	dir := t.TempDir()
+	if err := b.ExtractTo(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	wantFiles := map[string]string{
+		"bundle.json":                        "",
+		"https_example.com/index.html":       "index",
+		"https_example.com/sub/a.txt":        "a",
+		"https_example.com/search%3Fq=a%2Fb": "search",
+		"https_example.com_8443/escape.txt":  "escape",
+		"relative/relative/b.txt":            "b",
+	}
+	var gotFiles []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		gotFiles = append(gotFiles, rel)
+		if want := wantFiles[rel]; want != "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if string(data) != want {
+				t.Errorf("%s: got %q, want %q", rel, data, want)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []string
+	for f := range wantFiles {
+		want = append(want, f)
+	}
+	sort.Strings(gotFiles)
+	sort.Strings(want)
+	if !reflect.DeepEqual(gotFiles, want) {
+		t.Errorf("files: got %q, want %q", gotFiles, want)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, ExtractSpecFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec struct {
+		Version    string
+		PrimaryURL string
+		Exchanges  []struct {
+			URL     string
+			Status  int
+			File    string
+			Headers map[string]string
+		}
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatal(err)
+	}
+	if spec.Version != "b2" || spec.PrimaryURL != "https://example.com/" {
+		t.Errorf("got version %q and primary URL %q", spec.Version, spec.PrimaryURL)
+	}
+	if len(spec.Exchanges) != len(b.Exchanges) {
+		t.Fatalf("got %d exchanges, want %d", len(spec.Exchanges), len(b.Exchanges))
+	}
+	if e := spec.Exchanges[2]; e.URL != "https://example.com/search?q=a/b" || e.File != "https_example.com/search%3Fq=a%2Fb" {
+		t.Errorf("exchanges[2]: got URL %q and file %q", e.URL, e.File)
+	}
+	redirect := spec.Exchanges[5]
+	wantHeaders := map[string]string{"Content-Type": "", "Location": "/", "Vary": "A, B"}
+	if redirect.Status != 301 || redirect.File != "" || !reflect.DeepEqual(redirect.Headers, wantHeaders) {
+		t.Errorf("exchanges[5]: got %+v", redirect)
+	}
+}
+
+func TestExtractToConflict(t *testing.T) {
+	b := &Bundle{
+		Version: version.VersionB2,
+		Exchanges: []*Exchange{
+			createTestExchange("https://example.com/", "a"),
+			createTestExchange("https://example.com/index.html", "b"),
+		},
+	}
+	if err := b.ExtractTo(t.TempDir()); err == nil {
+		t.Error("ExtractTo unexpectedly succeeded")
+	}
+}
@@ -0,0 +1,223 @@
+package bundle
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Severity is the severity of a Finding.
+type Severity int
+
+const (
+	// SeverityWarning marks a problem that does not prevent the bundle from
+	// being written or loaded, but is likely a mistake.
+	SeverityWarning Severity = iota
+	// SeverityError marks a problem that makes the bundle invalid.
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// Finding is a problem found by Lint.
+type Finding struct {
+	Severity Severity
+	// URL is the request URL of the exchange the finding is about, or nil
+	// if it is about the bundle as a whole.
+	URL     *url.URL
+	Message string
+}
+
+func (f *Finding) String() string {
+	if f.URL == nil {
+		return fmt.Sprintf("%v: %s", f.Severity, f.Message)
+	}
+	return fmt.Sprintf("%v: %v: %s", f.Severity, f.URL, f.Message)
+}
+
+// LintReport is the list of findings returned by Lint.
+type LintReport []*Finding
+
+// HasErrors reports whether r has a finding of SeverityError.
+func (r LintReport) HasErrors() bool {
+	for _, f := range r {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+type linter struct {
+	report LintReport
+}
+
+func (l *linter) add(s Severity, u *url.URL, format string, args ...interface{}) {
+	l.report = append(l.report, &Finding{s, u, fmt.Sprintf(format, args...)})
+}
+
+// isTokenChar reports whether c may appear in an HTTP token (RFC 7230
+// section 3.2.6).
+func isTokenChar(c byte) bool {
+	if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' {
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0
+}
+
+func isValidFieldName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isTokenChar(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isValidFieldValue reports whether value is a field-value of RFC 7230
+// section 3.2 consisting of ASCII characters, which is what the bundle
+// decoder accepts. Obsolete line folding is not allowed.
+func isValidFieldValue(value string) bool {
+	if value != strings.Trim(value, " \t") {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c >= 0x7f || (c < 0x20 && c != '\t') {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *linter) lintURL(u *url.URL, what string) {
+	if u.Fragment != "" {
+		l.add(SeverityError, u, "%s contains a fragment", what)
+	}
+	if u.User != nil {
+		l.add(SeverityError, u, "%s contains credentials", what)
+	}
+}
+
+func (l *linter) lintHeader(u *url.URL, h http.Header, what string) {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if strings.HasPrefix(name, ":") {
+			l.add(SeverityError, u, "%s has pseudo-header %q; pseudo-headers are generated by the encoder", what, name)
+			continue
+		}
+		if !isValidFieldName(name) {
+			l.add(SeverityError, u, "%s has invalid header name %q", what, name)
+			continue
+		}
+		for _, value := range h[name] {
+			if !isValidFieldValue(value) {
+				l.add(SeverityError, u, "%s header %q has invalid value %q", what, name, value)
+			}
+		}
+	}
+}
+
+// Lint checks b for problems and returns all of them, unlike Validate which
+// only reports the first one. Problems that make the bundle impossible to
+// write or load, or that violate the spec, are errors; likely mistakes are
+// warnings.
+func Lint(b *Bundle) LintReport {
+	l := &linter{}
+
+	if b.PrimaryURL != nil {
+		l.lintURL(b.PrimaryURL, "primary URL")
+	}
+	if b.ManifestURL != nil {
+		l.lintURL(b.ManifestURL, "manifest URL")
+		if !b.Version.SupportsManifestSection() {
+			l.add(SeverityError, nil, "version %s does not support the manifest URL", b.Version)
+		}
+	}
+
+	byURL := make(map[string][]*Exchange)
+	var urls []string // in the order of first appearance
+	numAbs, numRel := 0, 0
+	for _, e := range b.Exchanges {
+		u := e.Request.URL
+		l.lintURL(u, "request URL")
+		if u.IsAbs() {
+			numAbs++
+		} else {
+			numRel++
+		}
+		key := u.String()
+		if _, exists := byURL[key]; !exists {
+			urls = append(urls, key)
+		}
+		byURL[key] = append(byURL[key], e)
+
+		l.lintHeader(u, e.Request.Header, "request")
+		l.lintHeader(u, e.Response.Header, "response")
+		bodyLen := int64(len(e.Response.Body))
+		if e.Response.BodySource != nil {
+			bodyLen = e.Response.BodySource.Len()
+		}
+		if bodyLen > 0 && e.Response.Header.Get("Content-Type") == "" {
+			l.add(SeverityWarning, u, "response has a body but no Content-Type header")
+		}
+	}
+	if numAbs > 0 && numRel > 0 {
+		l.add(SeverityWarning, nil, "bundle mixes %d absolute and %d relative request URLs", numAbs, numRel)
+	}
+
+	for _, key := range urls {
+		es := byURL[key]
+		if len(es) == 1 {
+			continue
+		}
+		hasVariants := true
+		for _, e := range es {
+			if e.Response.Header.Get("Variants") == "" || e.Response.Header.Get("Variant-Key") == "" {
+				hasVariants = false
+			}
+		}
+		if !hasVariants {
+			l.add(SeverityError, es[0].Request.URL, "%d exchanges for the same URL without Variants and Variant-Key headers", len(es))
+		} else if !b.Version.SupportsVariants() {
+			l.add(SeverityError, es[0].Request.URL, "%d exchanges for the same URL, but version %s does not support variants", len(es), b.Version)
+		}
+	}
+
+	if b.PrimaryURL != nil {
+		if _, ok := byURL[b.PrimaryURL.String()]; !ok {
+			l.add(SeverityError, nil, "no exchange for primary URL %v", b.PrimaryURL)
+		}
+	}
+	if b.ManifestURL != nil {
+		if _, ok := byURL[b.ManifestURL.String()]; !ok {
+			l.add(SeverityWarning, nil, "no exchange for manifest URL %v", b.ManifestURL)
+		}
+	}
+
+	for _, d := range b.Dependencies {
+		if err := d.Validate(); err != nil {
+			l.add(SeverityError, nil, "%s", strings.TrimPrefix(err.Error(), "bundle: "))
+		}
+	}
+
+	return l.report
+}
@@ -0,0 +1,170 @@
+package bundle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+)
+
+// ConflictPolicy specifies how Merge resolves a value that differs between
+// the merged bundles.
+type ConflictPolicy int
+
+const (
+	// ConflictError makes Merge fail.
+	ConflictError ConflictPolicy = iota
+	// ConflictKeepFirst keeps the value from the first bundle that has it.
+	ConflictKeepFirst
+	// ConflictKeepLast keeps the value from the last bundle that has it.
+	ConflictKeepLast
+)
+
+// MergeOptions specifies the conflict resolution of Merge. Values that are
+// identical in all bundles never conflict.
+type MergeOptions struct {
+	// Exchanges resolves exchanges with the same URL (and the same
+	// Variant-Key, if any) but different responses. It is also used for
+	// dependencies with the same resource URL and unknown sections with the
+	// same name.
+	Exchanges ConflictPolicy
+	// PrimaryURL resolves bundles with different primary URLs.
+	PrimaryURL ConflictPolicy
+	// ManifestURL resolves bundles with different manifest URLs.
+	ManifestURL ConflictPolicy
+}
+
+// exchangeKey identifies an exchange within a bundle. Exchanges for the same
+// URL are distinguished by their Variant-Key.
+func exchangeKey(e *Exchange) string {
+	key := e.Request.URL.String()
+	if vk := e.Response.Header.Get("Variant-Key"); vk != "" {
+		key += " " + vk
+	}
+	return key
+}
+
+// resolveConflict returns whether the new value should replace the old one,
+// or an error described by format and args if conflicts are not allowed.
+func resolveConflict(policy ConflictPolicy, format string, args ...interface{}) (bool, error) {
+	switch policy {
+	case ConflictKeepFirst:
+		return false, nil
+	case ConflictKeepLast:
+		return true, nil
+	default:
+		return false, fmt.Errorf(format, args...)
+	}
+}
+
+func mergeURL(policy ConflictPolicy, what string, old, new *url.URL) (*url.URL, error) {
+	if old == nil || new == nil || old.String() == new.String() {
+		if old == nil {
+			return new, nil
+		}
+		return old, nil
+	}
+	replace, err := resolveConflict(policy, "bundle: conflicting %s: %v and %v", what, old, new)
+	if err != nil || !replace {
+		return old, err
+	}
+	return new, nil
+}
+
+// Merge combines bundles into a new bundle. All bundles must have the same
+// version. Conflicts are resolved as specified by opts. The signatures
+// sections of the inputs are dropped, since the merged bundle invalidates
+// them.
+func Merge(opts MergeOptions, bundles ...*Bundle) (*Bundle, error) {
+	if len(bundles) == 0 {
+		return nil, errors.New("bundle: no bundles to merge")
+	}
+
+	merged := &Bundle{Version: bundles[0].Version}
+	exchanges := make(map[string]int)    // exchangeKey => index in merged.Exchanges
+	dependencies := make(map[string]int) // resource URL => index in merged.Dependencies
+	unknownSections := make(map[string]int)
+	critical := make(map[string]struct{})
+
+	for _, b := range bundles {
+		if b.Version != merged.Version {
+			return nil, fmt.Errorf("bundle: cannot merge bundles of different versions %q and %q", merged.Version, b.Version)
+		}
+
+		var err error
+		if merged.PrimaryURL, err = mergeURL(opts.PrimaryURL, "primary URLs", merged.PrimaryURL, b.PrimaryURL); err != nil {
+			return nil, err
+		}
+		if merged.ManifestURL, err = mergeURL(opts.ManifestURL, "manifest URLs", merged.ManifestURL, b.ManifestURL); err != nil {
+			return nil, err
+		}
+
+		for _, e := range b.Exchanges {
+			key := exchangeKey(e)
+			i, exists := exchanges[key]
+			if !exists {
+				exchanges[key] = len(merged.Exchanges)
+				merged.Exchanges = append(merged.Exchanges, e)
+				continue
+			}
+			if reflect.DeepEqual(merged.Exchanges[i].Response, e.Response) {
+				continue
+			}
+			replace, err := resolveConflict(opts.Exchanges, "bundle: conflicting responses for %s", key)
+			if err != nil {
+				return nil, err
+			}
+			if replace {
+				merged.Exchanges[i] = e
+			}
+		}
+
+		for _, d := range b.Dependencies {
+			key := d.ResourceURL.String()
+			i, exists := dependencies[key]
+			if !exists {
+				dependencies[key] = len(merged.Dependencies)
+				merged.Dependencies = append(merged.Dependencies, d)
+				continue
+			}
+			if reflect.DeepEqual(merged.Dependencies[i], d) {
+				continue
+			}
+			replace, err := resolveConflict(opts.Exchanges, "bundle: conflicting dependencies for %s", key)
+			if err != nil {
+				return nil, err
+			}
+			if replace {
+				merged.Dependencies[i] = d
+			}
+		}
+
+		for _, s := range b.UnknownSections {
+			i, exists := unknownSections[s.Name]
+			if !exists {
+				unknownSections[s.Name] = len(merged.UnknownSections)
+				merged.UnknownSections = append(merged.UnknownSections, s)
+				continue
+			}
+			if bytes.Equal(merged.UnknownSections[i].Contents, s.Contents) {
+				continue
+			}
+			replace, err := resolveConflict(opts.Exchanges, "bundle: conflicting contents of section %q", s.Name)
+			if err != nil {
+				return nil, err
+			}
+			if replace {
+				merged.UnknownSections[i] = s
+			}
+		}
+
+		for _, name := range b.Critical {
+			if _, exists := critical[name]; !exists {
+				critical[name] = struct{}{}
+				merged.Critical = append(merged.Critical, name)
+			}
+		}
+	}
+	return merged, nil
+}
@@ -0,0 +1,209 @@
+package bundle_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+
+	. "github.com/WICG/webpackage/go/bundle"
+	"github.com/WICG/webpackage/go/bundle/version"
+)
+
+func createTestExchange(rawURL, body string) *Exchange {
+	return &Exchange{
+		Request{URL: urlMustParse(rawURL)},
+		Response{
+			Status: 200,
+			Header: http.Header{"Content-Type": []string{"text/plain"}},
+			Body:   []byte(body),
+		},
+	}
+}
+
+func exchangeURLs(b *Bundle) []string {
+	var urls []string
+	for _, e := range b.Exchanges {
+		urls = append(urls, e.Request.URL.String())
+	}
+	return urls
+}
+
+func TestMerge(t *testing.T) {
+	a := &Bundle{
+		Version:    version.VersionB2,
+		PrimaryURL: urlMustParse("https://example.com/a"),
+		Exchanges: []*Exchange{
+			createTestExchange("https://example.com/a", "a"),
+			createTestExchange("https://example.com/shared", "shared"),
+			createTestExchange("https://example.com/conflict", "from a"),
+		},
+		Signatures: &Signatures{},
+	}
+	b := &Bundle{
+		Version: version.VersionB2,
+		Exchanges: []*Exchange{
+			createTestExchange("https://example.com/b", "b"),
+			createTestExchange("https://example.com/shared", "shared"),
+			createTestExchange("https://example.com/conflict", "from b"),
+		},
+	}
+
+	if _, err := Merge(MergeOptions{}, a, b); err == nil {
+		t.Error("Merge with conflicting exchanges unexpectedly succeeded")
+	}
+
+	for _, c := range []struct {
+		policy       ConflictPolicy
+		wantConflict string
+	}{
+		{ConflictKeepFirst, "from a"},
+		{ConflictKeepLast, "from b"},
+	} {
+		merged, err := Merge(MergeOptions{Exchanges: c.policy}, a, b)
+		if err != nil {
+			t.Fatalf("Merge unexpectedly failed: %v", err)
+		}
+		got := fmt.Sprint(exchangeURLs(merged))
+		want := "[https://example.com/a https://example.com/shared https://example.com/conflict https://example.com/b]"
+		if got != want {
+			t.Errorf("exchanges: got %s, want %s", got, want)
+		}
+		if body := string(merged.Exchanges[2].Response.Body); body != c.wantConflict {
+			t.Errorf("policy %v: got %q, want %q", c.policy, body, c.wantConflict)
+		}
+		if merged.PrimaryURL.String() != "https://example.com/a" {
+			t.Errorf("PrimaryURL: got %v", merged.PrimaryURL)
+		}
+		if merged.Signatures != nil {
+			t.Error("Merge should drop the signatures")
+		}
+	}
+
+	b.PrimaryURL = urlMustParse("https://example.com/b")
+	if _, err := Merge(MergeOptions{Exchanges: ConflictKeepFirst}, a, b); err == nil {
+		t.Error("Merge with conflicting primary URLs unexpectedly succeeded")
+	}
+	merged, err := Merge(MergeOptions{Exchanges: ConflictKeepFirst, PrimaryURL: ConflictKeepLast}, a, b)
+	if err != nil {
+		t.Fatalf("Merge unexpectedly failed: %v", err)
+	}
+	if merged.PrimaryURL.String() != "https://example.com/b" {
+		t.Errorf("PrimaryURL: got %v, want %v", merged.PrimaryURL, b.PrimaryURL)
+	}
+
+	b.Version = version.VersionB1
+	if _, err := Merge(MergeOptions{Exchanges: ConflictKeepFirst, PrimaryURL: ConflictKeepLast}, a, b); err == nil {
+		t.Error("Merge of different versions unexpectedly succeeded")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	b := &Bundle{
+		Version:    version.VersionB2,
+		PrimaryURL: urlMustParse("https://example.com/"),
+		Exchanges: []*Exchange{
+			createTestExchange("https://example.com/", "index"),
+			createTestExchange("https://example.com/a.js", "a"),
+			createTestExchange("https://example.com/lib/b.js", "b"),
+			createTestExchange("https://example.com/style.css", "css"),
+		},
+	}
+
+	glob, err := GlobMatcher("https://example.com/*.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deepGlob, err := GlobMatcher("https://example.com/**.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		m              URLMatcher
+		want           string
+		wantPrimaryURL bool
+	}{
+		{glob, "[https://example.com/a.js]", false},
+		{deepGlob, "[https://example.com/a.js https://example.com/lib/b.js]", false},
+		{RegexpMatcher(regexp.MustCompile(`/$|\.css$`)), "[https://example.com/ https://example.com/style.css]", true},
+	} {
+		filtered := Filter(b, c.m)
+		if got := fmt.Sprint(exchangeURLs(filtered)); got != c.want {
+			t.Errorf("got %s, want %s", got, c.want)
+		}
+		if (filtered.PrimaryURL != nil) != c.wantPrimaryURL {
+			t.Errorf("%s: PrimaryURL: got %v", c.want, filtered.PrimaryURL)
+		}
+	}
+}
+
+func TestSplitByPrefix(t *testing.T) {
+	b := &Bundle{
+		Version: version.VersionB2,
+		Exchanges: []*Exchange{
+			createTestExchange("https://example.com/", "index"),
+			createTestExchange("https://example.com/a/1", "a1"),
+			createTestExchange("https://example.com/b/1", "b1"),
+			createTestExchange("https://example.com/a/2", "a2"),
+		},
+	}
+	bs := SplitByPrefix(b, []string{"https://example.com/a/", "https://example.com/b/"})
+	want := []string{
+		"[https://example.com/a/1 https://example.com/a/2]",
+		"[https://example.com/b/1]",
+		"[https://example.com/]",
+	}
+	if len(bs) != len(want) {
+		t.Fatalf("got %d bundles, want %d", len(bs), len(want))
+	}
+	for i := range want {
+		if got := fmt.Sprint(exchangeURLs(bs[i])); got != want[i] {
+			t.Errorf("bundle #%d: got %s, want %s", i, got, want[i])
+		}
+	}
+}
+
+func TestSplitBySize(t *testing.T) {
+	for _, ver := range version.AllVersions {
+		b := &Bundle{
+			Version:    ver,
+			PrimaryURL: urlMustParse("https://example.com/0"),
+		}
+		for i := 0; i < 50; i++ {
+			body := bytes.Repeat([]byte{'x'}, 100*(i%7))
+			b.Exchanges = append(b.Exchanges, createTestExchange(fmt.Sprintf("https://example.com/%d", i), string(body)))
+		}
+
+		const maxSize = 2000
+		bs, err := SplitBySize(b, maxSize)
+		if err != nil {
+			t.Fatalf("SplitBySize unexpectedly failed: %v", err)
+		}
+		if len(bs) < 2 {
+			t.Errorf("got %d bundles, want more than one", len(bs))
+		}
+		var urls []string
+		for i, sb := range bs {
+			var buf bytes.Buffer
+			n, err := sb.WriteTo(&buf)
+			if err != nil {
+				t.Fatalf("WriteTo unexpectedly failed: %v", err)
+			}
+			if n > maxSize {
+				t.Errorf("bundle #%d is %d bytes, exceeding %d bytes", i, n, maxSize)
+			}
+			if l, err := sb.EncodedLen(); err != nil || l != n {
+				t.Errorf("EncodedLen: got (%d, %v), want (%d, nil)", l, err, n)
+			}
+			urls = append(urls, exchangeURLs(sb)...)
+		}
+		if got, want := fmt.Sprint(urls), fmt.Sprint(exchangeURLs(b)); got != want {
+			t.Errorf("exchanges: got %s, want %s", got, want)
+		}
+
+		if _, err := SplitBySize(b, 100); err == nil {
+			t.Error("SplitBySize with a too small budget unexpectedly succeeded")
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package bundle
+
+import (
+	"errors"
+	"io"
+	"net/url"
+
+	"github.com/WICG/webpackage/go/bundle/version"
+)
+
+// ErrExchangeNotFound is returned by Reader.FindExchange when the bundle has
+// no exchange for the requested URL.
+var ErrExchangeNotFound = errors.New("bundle: exchange not found")
+
+// Reader provides random access to the exchanges of a bundle stored in an
+// io.ReaderAt. Open decodes only the metadata sections of the bundle;
+// responses are read and decoded on demand, so the memory needed to look up
+// a single exchange does not depend on the size of the bundle.
+type Reader struct {
+	ra    io.ReaderAt
+	opts  ReadOptions
+	meta  *meta
+	byURL map[string][]int // URL string => indices in meta.requests
+}
+
+// ReadOptions limits the resources used to decode a bundle, for bundles from
+// untrusted sources. Bundles exceeding a limit fail to decode with an error.
+// A zero value means no limit.
+type ReadOptions struct {
+	// MaxSize is the maximum size of the whole bundle in bytes.
+	MaxSize int64
+	// MaxExchanges is the maximum number of exchanges in the index.
+	MaxExchanges int
+	// MaxHeaders is the maximum number of header fields of a response,
+	// excluding the :status pseudo-header.
+	MaxHeaders int
+	// MaxHeaderBytes is the maximum size of the CBOR-encoded headers of a
+	// response in bytes.
+	MaxHeaderBytes int
+	// MaxBodySize is the maximum size of a response body in bytes.
+	MaxBodySize int64
+}
+
+// Open parses the metadata of the bundle of the given size stored in ra.
+// The returned Reader reads responses from ra, so ra must stay valid while
+// the Reader is in use.
+func Open(ra io.ReaderAt, size int64) (*Reader, error) {
+	return OpenWithOptions(ra, size, ReadOptions{})
+}
+
+// OpenWithOptions is like Open, but fails if the bundle exceeds the limits
+// of opts. The limits on responses are checked when they are read.
+func OpenWithOptions(ra io.ReaderAt, size int64, opts ReadOptions) (*Reader, error) {
+	m, err := loadMetadata(ra, size, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	byURL := make(map[string][]int)
+	for i, req := range m.requests {
+		u := req.URL.String()
+		byURL[u] = append(byURL[u], i)
+	}
+	return &Reader{ra: ra, opts: opts, meta: m, byURL: byURL}, nil
+}
+
+func (br *Reader) Version() version.Version { return br.meta.version }
+func (br *Reader) PrimaryURL() *url.URL     { return br.meta.primaryURL }
+func (br *Reader) ManifestURL() *url.URL    { return br.meta.manifestURL }
+func (br *Reader) Signatures() *Signatures  { return br.meta.signatures }
+
+// NumExchanges returns the number of exchanges listed in the bundle's index.
+func (br *Reader) NumExchanges() int {
+	return len(br.meta.requests)
+}
+
+// Request returns the request of the i-th exchange in the index. This does not
+// read the response.
+func (br *Reader) Request(i int) Request {
+	return br.meta.requests[i].Request
+}
+
+// ReadExchange reads and decodes the i-th exchange in the index.
+func (br *Reader) ReadExchange(i int) (*Exchange, error) {
+	req := br.meta.requests[i]
+	res, err := loadResponse(req, br.ra, &br.opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Exchange{Request: req.Request, Response: res}, nil
+}
+
+// FindExchange reads and decodes the first exchange whose request URL is u.
+// If there is no such exchange, it returns ErrExchangeNotFound.
+func (br *Reader) FindExchange(u *url.URL) (*Exchange, error) {
+	is, ok := br.byURL[u.String()]
+	if !ok {
+		return nil, ErrExchangeNotFound
+	}
+	return br.ReadExchange(is[0])
+}
+
+// ReadAll decodes all the exchanges and returns them as a Bundle.
+func (br *Reader) ReadAll() (*Bundle, error) {
+	es := []*Exchange{}
+	for i := range br.meta.requests {
+		e, err := br.ReadExchange(i)
+		if err != nil {
+			return nil, err
+		}
+		es = append(es, e)
+	}
+
+	m := br.meta
+	return &Bundle{
+		Version:         m.version,
+		PrimaryURL:      m.primaryURL,
+		Exchanges:       es,
+		ManifestURL:     m.manifestURL,
+		Signatures:      m.signatures,
+		Dependencies:    m.dependencies,
+		Critical:        m.critical,
+		UnknownSections: m.unknown,
+	}, nil
+}
@@ -0,0 +1,127 @@
+package bundle
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Server is an http.Handler that serves the exchanges of a bundle, the way a
+// browser would load them from the bundle.
+//
+// Requests are matched against the request URLs of the exchanges. Relative
+// URLs in the bundle (e.g. those generated by gen-bundle without -baseURL)
+// are matched against the request path on any host. For 200 responses, HEAD,
+// conditional requests against the stored ETag and Last-Modified headers, and
+// byte range requests are handled by http.ServeContent.
+type Server struct {
+	// Origin, if non-nil, is an origin (e.g. "https://example.com") whose
+	// exchanges are served on the host the server is accessed through, so
+	// that the bundle can be previewed on a local server. Absolute Location
+	// headers pointing to Origin are rewritten into paths.
+	Origin *url.URL
+
+	abs map[string]*Exchange // absolute URL => exchange
+	rel map[string]*Exchange // request URI of relative URL => exchange
+}
+
+var _ = http.Handler(&Server{})
+
+// NewServer returns a Server for the exchanges of b. If b has multiple
+// exchanges for a URL, the first one is served.
+func NewServer(b *Bundle) *Server {
+	s := &Server{
+		abs: make(map[string]*Exchange),
+		rel: make(map[string]*Exchange),
+	}
+	root := &url.URL{Path: "/"}
+	for _, e := range b.Exchanges {
+		m, key := s.abs, e.Request.URL.String()
+		if !e.Request.URL.IsAbs() {
+			m, key = s.rel, root.ResolveReference(e.Request.URL).RequestURI()
+		}
+		if _, exists := m[key]; !exists {
+			m[key] = e
+		}
+	}
+	return s
+}
+
+// findExchange returns the exchange to serve for r, or nil.
+func (s *Server) findExchange(r *http.Request) *Exchange {
+	u := &url.URL{Scheme: "http", Host: r.Host}
+	if r.TLS != nil {
+		u.Scheme = "https"
+	}
+	if s.Origin != nil {
+		u.Scheme = s.Origin.Scheme
+		u.Host = s.Origin.Host
+	}
+	u = u.ResolveReference(&url.URL{Path: r.URL.Path, RawPath: r.URL.RawPath, RawQuery: r.URL.RawQuery})
+	if e, ok := s.abs[u.String()]; ok {
+		return e
+	}
+	if e, ok := s.rel[r.URL.RequestURI()]; ok {
+		return e
+	}
+	return nil
+}
+
+// rewriteLocation turns an absolute URL on s.Origin into a path, so that
+// redirects stay on the serving host.
+func (s *Server) rewriteLocation(location string) string {
+	if s.Origin == nil {
+		return location
+	}
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme != s.Origin.Scheme || u.Host != s.Origin.Host {
+		return location
+	}
+	return u.RequestURI()
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "405 method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	e := s.findExchange(r)
+	if e == nil {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := e.Response.BodyBytes()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h := w.Header()
+	for name, values := range e.Response.Header {
+		h[name] = append([]string(nil), values...)
+	}
+	if loc := h.Get("Location"); loc != "" {
+		h.Set("Location", s.rewriteLocation(loc))
+	}
+
+	if e.Response.Status != http.StatusOK {
+		w.WriteHeader(e.Response.Status)
+		if r.Method != http.MethodHead {
+			w.Write(body)
+		}
+		return
+	}
+
+	// http.ServeContent computes Content-Length itself, which differs from
+	// the stored one for range requests.
+	h.Del("Content-Length")
+	lastModified, _ := http.ParseTime(h.Get("Last-Modified"))
+	name := e.Request.URL.Path
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	http.ServeContent(w, r, name, lastModified, bytes.NewReader(body))
+}
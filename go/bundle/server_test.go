@@ -0,0 +1,194 @@
+package bundle_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/WICG/webpackage/go/bundle"
+	"github.com/WICG/webpackage/go/bundle/version"
+)
+
+func createTestServerBundle() *Bundle {
+	return &Bundle{
+		Version: version.VersionB2,
+		Exchanges: []*Exchange{
+			&Exchange{
+				Request{URL: urlMustParse("https://bundle.example.com/index.html")},
+				Response{
+					Status: 200,
+					Header: http.Header{
+						"Content-Type":   []string{"text/html"},
+						"Content-Length": []string{"13"},
+						"Etag":           []string{`"v1"`},
+						"Last-Modified":  []string{"Mon, 02 Jan 2006 15:04:05 GMT"},
+					},
+					Body: []byte("hello, world!"),
+				},
+			},
+			&Exchange{
+				Request{URL: urlMustParse("https://bundle.example.com/old")},
+				Response{
+					Status: 301,
+					Header: http.Header{"Location": []string{"https://bundle.example.com/index.html"}},
+				},
+			},
+			&Exchange{
+				Request{URL: urlMustParse("style.css")},
+				Response{
+					Status: 200,
+					Header: http.Header{"Content-Type": []string{"text/css"}},
+					Body:   []byte("body{}"),
+				},
+			},
+		},
+	}
+}
+
+func TestServer(t *testing.T) {
+	s := NewServer(createTestServerBundle())
+
+	cases := []struct {
+		name       string
+		method     string
+		target     string
+		header     http.Header
+		wantStatus int
+		wantBody   string
+		wantHeader map[string]string
+	}{
+		{
+			name:       "GET",
+			method:     "GET",
+			target:     "https://bundle.example.com/index.html",
+			wantStatus: 200,
+			wantBody:   "hello, world!",
+			wantHeader: map[string]string{"Content-Type": "text/html", "Etag": `"v1"`},
+		},
+		{
+			name:       "HEAD",
+			method:     "HEAD",
+			target:     "https://bundle.example.com/index.html",
+			wantStatus: 200,
+			wantBody:   "",
+			wantHeader: map[string]string{"Content-Length": "13"},
+		},
+		{
+			name:       "If-None-Match",
+			method:     "GET",
+			target:     "https://bundle.example.com/index.html",
+			header:     http.Header{"If-None-Match": []string{`"v1"`}},
+			wantStatus: 304,
+			wantBody:   "",
+		},
+		{
+			name:       "If-Modified-Since",
+			method:     "GET",
+			target:     "https://bundle.example.com/index.html",
+			header:     http.Header{"If-Modified-Since": []string{"Tue, 03 Jan 2006 00:00:00 GMT"}},
+			wantStatus: 304,
+			wantBody:   "",
+		},
+		{
+			name:       "Range",
+			method:     "GET",
+			target:     "https://bundle.example.com/index.html",
+			header:     http.Header{"Range": []string{"bytes=7-11"}},
+			wantStatus: 206,
+			wantBody:   "world",
+			wantHeader: map[string]string{"Content-Range": "bytes 7-11/13", "Content-Length": "5"},
+		},
+		{
+			name:       "Redirect",
+			method:     "GET",
+			target:     "https://bundle.example.com/old",
+			wantStatus: 301,
+			wantHeader: map[string]string{"Location": "https://bundle.example.com/index.html"},
+		},
+		{
+			name:       "RelativeURL",
+			method:     "GET",
+			target:     "http://localhost:8080/style.css",
+			wantStatus: 200,
+			wantBody:   "body{}",
+		},
+		{
+			name:       "OtherHost",
+			method:     "GET",
+			target:     "http://localhost:8080/index.html",
+			wantStatus: 404,
+		},
+		{
+			name:       "POST",
+			method:     "POST",
+			target:     "https://bundle.example.com/index.html",
+			wantStatus: 405,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.target, nil)
+			for k, v := range c.header {
+				req.Header[k] = v
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, req)
+			res := w.Result()
+			if res.StatusCode != c.wantStatus {
+				t.Errorf("status: got %d, want %d", res.StatusCode, c.wantStatus)
+			}
+			body, _ := ioutil.ReadAll(res.Body)
+			if c.wantBody != "" || c.wantStatus == 304 || c.method == "HEAD" {
+				if string(body) != c.wantBody {
+					t.Errorf("body: got %q, want %q", body, c.wantBody)
+				}
+			}
+			for k, v := range c.wantHeader {
+				if got := res.Header.Get(k); got != v {
+					t.Errorf("header %s: got %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestServerWithOrigin(t *testing.T) {
+	s := NewServer(createTestServerBundle())
+	s.Origin = urlMustParse("https://bundle.example.com")
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "http://localhost:8080/index.html", nil))
+	if w.Code != 200 || w.Body.String() != "hello, world!" {
+		t.Errorf("got (%d, %q), want (200, %q)", w.Code, w.Body.String(), "hello, world!")
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "http://localhost:8080/old", nil))
+	if loc := w.Header().Get("Location"); loc != "/index.html" {
+		t.Errorf("Location: got %q, want %q", loc, "/index.html")
+	}
+}
+
+func TestServerBodySource(t *testing.T) {
+	b := createTestServerBundle()
+	for _, e := range b.Exchanges {
+		e.Response.BodySource = BytesBody(e.Response.Body)
+		e.Response.Body = nil
+	}
+	s := NewServer(b)
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "https://bundle.example.com/index.html", nil))
+	if w.Code != 200 || w.Body.String() != "hello, world!" {
+		t.Errorf("got (%d, %q), want (200, %q)", w.Code, w.Body.String(), "hello, world!")
+	}
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "https://bundle.example.com/index.html", nil)
+	req.Header.Set("Range", "bytes=7-11")
+	s.ServeHTTP(w, req)
+	if w.Code != 206 || w.Body.String() != "world" {
+		t.Errorf("range request: got (%d, %q), want (206, %q)", w.Code, w.Body.String(), "world")
+	}
+}
@@ -169,12 +169,20 @@ func (s *Signer) sign(signed []byte) ([]byte, error) {
 		}
 	}
 
-	return s.Algorithm.Sign(generateSignedMessage(signed, s.Version))
+	msg, err := generateSignedMessage(signed, s.Version)
+	if err != nil {
+		return nil, err
+	}
+	return s.Algorithm.Sign(msg)
 }
 
 // https://github.com/WICG/webpackage/issues/472#issuecomment-520080192
 // TODO: Update the above reference once we have spec text for this.
-func generateSignedMessage(signed []byte, ver version.Version) []byte {
+func generateSignedMessage(signed []byte, ver version.Version) ([]byte, error) {
+	contextString, err := ver.SignatureContextString()
+	if err != nil {
+		return nil, err
+	}
 	// The message is the concatenation of:
 	var buf bytes.Buffer
 	// 1. A string that consists of octet 32 (0x20) repeated 64 times.
@@ -182,10 +190,10 @@ func generateSignedMessage(signed []byte, ver version.Version) []byte {
 		buf.WriteByte(0x20)
 	}
 	// 2. A context string: "Web Package <version>".
-	buf.WriteString(ver.SignatureContextString())
+	buf.WriteString(contextString)
 	// 3. A single 0 byte which serves as a separator.
 	buf.WriteByte(0)
 	// 4. The signed bstr.
 	buf.Write(signed)
-	return buf.Bytes()
+	return buf.Bytes(), nil
 }
@@ -76,7 +76,10 @@ func (v *Verifier) VerifyExchange(e *bundle.Exchange) (*VerifyExchangeResult, er
 		return nil, errors.New("signature: header sha256 mismatch")
 	}
 
-	encoding := v.Version.MiceEncoding()
+	encoding, err := v.Version.MiceEncoding()
+	if err != nil {
+		return nil, err
+	}
 	if encoding.IntegrityIdentifier() != rh.PayloadIntegrityHeader {
 		return nil, errors.New("signature: integrity identifier mismatch")
 	}
@@ -114,7 +117,10 @@ func verifyVouchedSubset(vs *bundle.VouchedSubset, authorities []*certurl.Augmen
 	if err != nil {
 		return nil, fmt.Errorf("signature: unsupported certificate public key: %v", err)
 	}
-	msg := generateSignedMessage(vs.Signed, ver)
+	msg, err := generateSignedMessage(vs.Signed, ver)
+	if err != nil {
+		return nil, err
+	}
 	ok, err := verifier.Verify(msg, vs.Sig)
 	if err != nil {
 		return nil, err
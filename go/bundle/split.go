@@ -0,0 +1,165 @@
+package bundle
+
+import (
+	"fmt"
+	"strings"
+)
+
+// subBundle returns a bundle with the metadata of b and the exchanges es.
+// The primary URL is kept only if es has an exchange for it, unless the
+// bundle version requires a primary URL. Signatures are dropped.
+func subBundle(b *Bundle, es []*Exchange) *Bundle {
+	sub := &Bundle{
+		Version:         b.Version,
+		Exchanges:       es,
+		ManifestURL:     b.ManifestURL,
+		Dependencies:    b.Dependencies,
+		Critical:        b.Critical,
+		UnknownSections: b.UnknownSections,
+	}
+	if b.PrimaryURL != nil {
+		if b.Version.HasPrimaryURLFieldInHeader() {
+			sub.PrimaryURL = b.PrimaryURL
+		}
+		for _, e := range es {
+			if e.Request.URL.String() == b.PrimaryURL.String() {
+				sub.PrimaryURL = b.PrimaryURL
+				break
+			}
+		}
+	}
+	return sub
+}
+
+// Filter returns a new bundle with the exchanges of b whose URLs match m.
+// The signatures section is dropped.
+func Filter(b *Bundle, m URLMatcher) *Bundle {
+	es := []*Exchange{}
+	for _, e := range b.Exchanges {
+		if m(e.Request.URL) {
+			es = append(es, e)
+		}
+	}
+	return subBundle(b, es)
+}
+
+// SplitByPrefix splits b into len(prefixes)+1 bundles. Each exchange goes to
+// the bundle of the first prefix its URL starts with; exchanges matching no
+// prefix go to the last bundle. Each bundle keeps the metadata of b except
+// for the signatures section.
+func SplitByPrefix(b *Bundle, prefixes []string) []*Bundle {
+	ess := make([][]*Exchange, len(prefixes)+1)
+	for _, e := range b.Exchanges {
+		u := e.Request.URL.String()
+		i := len(prefixes)
+		for j, p := range prefixes {
+			if strings.HasPrefix(u, p) {
+				i = j
+				break
+			}
+		}
+		ess[i] = append(ess[i], e)
+	}
+
+	bs := make([]*Bundle, len(ess))
+	for i, es := range ess {
+		if es == nil {
+			es = []*Exchange{}
+		}
+		bs[i] = subBundle(b, es)
+	}
+	return bs
+}
+
+// splitSlack bounds the growth of the bundle header when exchanges are added
+// (wider CBOR integers for section lengths and array sizes).
+const splitSlack = 64
+
+// exchangeLenBound returns an upper bound of how much adding e grows an
+// encoded bundle.
+func exchangeLenBound(e *Exchange) (int64, error) {
+	headerCbor, err := e.Response.EncodeHeader()
+	if err != nil {
+		return 0, err
+	}
+	bodyLen := int64(len(e.Response.Body))
+	if e.Response.BodySource != nil {
+		bodyLen = e.Response.BodySource.Len()
+	}
+	// Response: array header, header byte string, and body byte string.
+	n := 1 + 9 + int64(len(headerCbor)) + 9 + bodyLen
+	// Index entry: URL, array header, variants value, offset and length.
+	n += 9 + int64(len(e.Request.URL.String())) + 9 + 9 + int64(len(e.Response.Header.Get("Variants"))) + 2*9
+	return n, nil
+}
+
+// SplitBySize splits b into bundles whose encoded sizes do not exceed
+// maxSize bytes, preserving the order of exchanges. Exchanges for the same
+// URL (i.e. variants) are kept in the same bundle. Each bundle keeps the
+// metadata of b except for the signatures section. It fails if the exchanges
+// for a single URL do not fit in maxSize.
+func SplitBySize(b *Bundle, maxSize int64) ([]*Bundle, error) {
+	// Group exchanges by URL, in order of first appearance.
+	var groups [][]*Exchange
+	groupIndex := make(map[string]int)
+	for _, e := range b.Exchanges {
+		u := e.Request.URL.String()
+		i, exists := groupIndex[u]
+		if !exists {
+			i = len(groups)
+			groupIndex[u] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], e)
+	}
+
+	var bs []*Bundle
+	var cur []*Exchange
+	// estimate is an upper bound of the encoded length of subBundle(b, cur).
+	// The exact length is computed only when the estimate exceeds maxSize.
+	var estimate int64
+	for _, g := range groups {
+		var glen int64
+		for _, e := range g {
+			n, err := exchangeLenBound(e)
+			if err != nil {
+				return nil, err
+			}
+			glen += n
+		}
+
+		if len(cur) > 0 && estimate+glen > maxSize {
+			candidate := append(cur[:len(cur):len(cur)], g...)
+			n, err := subBundle(b, candidate).EncodedLen()
+			if err != nil {
+				return nil, err
+			}
+			if n <= maxSize {
+				cur = candidate
+				estimate = n + splitSlack
+				continue
+			}
+			bs = append(bs, subBundle(b, cur))
+			cur = nil
+		}
+
+		if len(cur) == 0 {
+			n, err := subBundle(b, g).EncodedLen()
+			if err != nil {
+				return nil, err
+			}
+			if n > maxSize {
+				return nil, fmt.Errorf("bundle: a bundle with only %v is %d bytes, exceeding %d bytes", g[0].Request.URL, n, maxSize)
+			}
+			cur = g
+			estimate = n + splitSlack
+			continue
+		}
+		cur = append(cur, g...)
+		estimate += glen
+	}
+	if len(cur) > 0 {
+		bs = append(bs, subBundle(b, cur))
+	}
+	return bs, nil
+}
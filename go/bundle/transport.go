@@ -0,0 +1,94 @@
+package bundle
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+// Transport is an http.RoundTripper that answers GET and HEAD requests from
+// the exchanges of a set of bundles. It lets HTTP clients replay a recorded
+// site (e.g. a bundle generated by gen-bundle -har) without network access.
+//
+// Requests are matched against the absolute request URLs of the exchanges;
+// exchanges with relative URLs are never matched.
+type Transport struct {
+	// Fallback, if non-nil, handles the requests that no bundle has an
+	// exchange for. If nil, those requests get a synthetic
+	// 504 Gateway Timeout response.
+	Fallback http.RoundTripper
+
+	exchanges map[string]*Exchange
+}
+
+var _ = http.RoundTripper(&Transport{})
+
+// NewTransport returns a Transport serving the exchanges of bundles. If
+// multiple exchanges have the same URL, the first one is served.
+func NewTransport(bundles ...*Bundle) *Transport {
+	t := &Transport{exchanges: make(map[string]*Exchange)}
+	for _, b := range bundles {
+		for _, e := range b.Exchanges {
+			if !e.Request.URL.IsAbs() {
+				continue
+			}
+			key := e.Request.URL.String()
+			if _, exists := t.exchanges[key]; !exists {
+				t.exchanges[key] = e
+			}
+		}
+	}
+	return t
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var e *Exchange
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		e = t.exchanges[req.URL.String()]
+	}
+	if e == nil && t.Fallback != nil {
+		// The fallback closes the request body.
+		return t.Fallback.RoundTrip(req)
+	}
+	// As required of http.RoundTrippers, close the request body.
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	if e == nil {
+		body := []byte(fmt.Sprintf("bundle: no exchange for %s %s\n", req.Method, req.URL))
+		header := http.Header{"Content-Type": []string{"text/plain; charset=utf-8"}}
+		return newResponse(req, http.StatusGatewayTimeout, header, body), nil
+	}
+
+	body, err := e.Response.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+	header := e.Response.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return newResponse(req, e.Response.Status, header, body), nil
+}
+
+// newResponse creates an http.Response for req. For HEAD requests, body is
+// used only to compute the content length.
+func newResponse(req *http.Request, status int, header http.Header, body []byte) *http.Response {
+	res := &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+	if req.Method == http.MethodHead {
+		res.Body = http.NoBody
+	}
+	return res
+}
@@ -0,0 +1,146 @@
+package bundle_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/WICG/webpackage/go/bundle"
+	"github.com/WICG/webpackage/go/bundle/version"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestTransport(t *testing.T) {
+	b1 := createTestBundle(t, version.VersionB2)
+	b2 := &Bundle{
+		Version: version.VersionB2,
+		Exchanges: []*Exchange{
+			&Exchange{
+				Request{URL: urlMustParse("https://bundle.example.com/")},
+				Response{Status: 200, Header: http.Header{}, Body: []byte("shadowed")},
+			},
+			&Exchange{
+				Request{URL: urlMustParse("https://other.example.com/a?q=1")},
+				Response{Status: 404, Header: http.Header{}, Body: []byte("not found")},
+			},
+		},
+	}
+	client := &http.Client{Transport: NewTransport(b1, b2)}
+
+	cases := []struct {
+		url        string
+		wantStatus int
+		wantBody   string
+	}{
+		{"https://bundle.example.com/", 200, "hello, world!"},
+		{"https://other.example.com/a?q=1", 404, "not found"},
+		{"https://other.example.com/a", 504, "bundle: no exchange for GET https://other.example.com/a\n"},
+	}
+	for _, c := range cases {
+		res, err := client.Get(c.url)
+		if err != nil {
+			t.Fatalf("Get(%q) unexpectedly failed: %v", c.url, err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != c.wantStatus || string(body) != c.wantBody {
+			t.Errorf("Get(%q): got (%d, %q), want (%d, %q)", c.url, res.StatusCode, body, c.wantStatus, c.wantBody)
+		}
+	}
+
+	res, err := client.Head("https://bundle.example.com/")
+	if err != nil {
+		t.Fatalf("Head unexpectedly failed: %v", err)
+	}
+	if res.StatusCode != 200 || res.Header.Get("Content-Type") != "text/html" {
+		t.Errorf("Head: got (%d, %v)", res.StatusCode, res.Header)
+	}
+}
+
+func TestTransportFallback(t *testing.T) {
+	errFallback := errors.New("fallback called")
+	tr := NewTransport(createTestBundle(t, version.VersionB2))
+	tr.Fallback = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errFallback
+	})
+	client := &http.Client{Transport: tr}
+
+	if _, err := client.Get("https://bundle.example.com/"); err != nil {
+		t.Errorf("Get for bundled URL unexpectedly failed: %v", err)
+	}
+	if _, err := client.Get("https://bundle.example.com/missing"); !errors.Is(err, errFallback) {
+		t.Errorf("Get for missing URL: got %v, want %v", err, errFallback)
+	}
+}
+
+// closeRecorder records whether the request body was closed.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestTransportClosesRequestBody(t *testing.T) {
+	tr := NewTransport(createTestBundle(t, version.VersionB2))
+	for _, rawURL := range []string{"https://bundle.example.com/", "https://unknown.example.com/"} {
+		body := &closeRecorder{Reader: strings.NewReader("data")}
+		req, err := http.NewRequest(http.MethodPost, rawURL, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if !body.closed {
+			t.Errorf("%s: request body not closed", rawURL)
+		}
+	}
+}
+
+func TestTransportBodySource(t *testing.T) {
+	b := &Bundle{
+		Version: version.VersionB2,
+		Exchanges: []*Exchange{
+			&Exchange{
+				Request{URL: urlMustParse("https://bundle.example.com/")},
+				Response{Status: 200, Header: http.Header{}, BodySource: BytesBody([]byte("streamed"))},
+			},
+			&Exchange{
+				Request{URL: urlMustParse("https://bundle.example.com/short")},
+				Response{Status: 200, Header: http.Header{}, BodySource: ReaderBody(strings.NewReader("short"), 10)},
+			},
+		},
+	}
+	client := &http.Client{Transport: NewTransport(b)}
+
+	res, err := client.Get("https://bundle.example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "streamed" || res.ContentLength != int64(len("streamed")) {
+		t.Errorf("got body %q with length %d, want %q", body, res.ContentLength, "streamed")
+	}
+
+	if _, err := client.Get("https://bundle.example.com/short"); err == nil {
+		t.Error("Get with an unreadable body unexpectedly succeeded")
+	}
+}
@@ -0,0 +1,54 @@
+package bundle
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// URLMatcher reports whether a URL is selected.
+type URLMatcher func(u *url.URL) bool
+
+// GlobMatcher returns a URLMatcher that matches the whole URL string against
+// a glob pattern. In the pattern, "**" matches any sequence of characters,
+// "*" matches any sequence of characters other than '/', and "?" matches a
+// single character other than '/'. For example,
+// "https://example.com/**.js" matches all JavaScript files of the origin.
+func GlobMatcher(pattern string) (URLMatcher, error) {
+	re, err := globToRegexp(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return RegexpMatcher(re), nil
+}
+
+// RegexpMatcher returns a URLMatcher that reports whether the URL string
+// contains a match of re.
+func RegexpMatcher(re *regexp.Regexp) URLMatcher {
+	return func(u *url.URL) bool {
+		return re.MatchString(u.String())
+	}
+}
+
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	rs := []rune(pattern)
+	for i := 0; i < len(rs); i++ {
+		switch rs[i] {
+		case '*':
+			if i+1 < len(rs) && rs[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(rs[i])))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
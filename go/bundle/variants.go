@@ -0,0 +1,271 @@
+package bundle
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Lookup returns the exchange a client sending the request headers header
+// would get for u, or ErrExchangeNotFound if b has no exchange for u.
+//
+// If the exchanges for u have Variants and Variant-Key headers, the
+// exchange is selected by the cache behaviour of
+// https://httpwg.org/http-extensions/draft-ietf-httpbis-variants.html#cache:
+// the acceptable values of each variant axis are sorted by the client's
+// preference, followed by the first available value of the axis as the
+// default, and the first exchange whose Variant-Key matches a combination of
+// them, in order of preference, is returned. Accept, Accept-Encoding and
+// Accept-Language are negotiated by their proactive negotiation rules; for
+// other headers, the request header values are matched exactly. Without
+// Variants, the first exchange for u is returned.
+func (b *Bundle) Lookup(u *url.URL, header http.Header) (*Exchange, error) {
+	var es []*Exchange
+	for _, e := range b.Exchanges {
+		if e.Request.URL.String() == u.String() {
+			es = append(es, e)
+		}
+	}
+	if len(es) == 0 {
+		return nil, ErrExchangeNotFound
+	}
+	variantsValue := normalizeHeaderValues(es[0].Response.Header.Values("Variants"))
+	if variantsValue == "" {
+		return es[0], nil
+	}
+	variants, err := parseVariants(variantsValue)
+	if err != nil {
+		return nil, fmt.Errorf("bundle: cannot parse Variants header value %q: %v", variantsValue, err)
+	}
+	if _, err := variants.numberOfPossibleKeys(); err != nil {
+		return nil, fmt.Errorf("bundle: invalid Variants header value %q: %v", variantsValue, err)
+	}
+
+	variantKeys := make([][][]string, len(es))
+	for i, e := range es {
+		variantKeys[i], err = parseListOfStringLists(normalizeHeaderValues(e.Response.Header.Values("Variant-Key")))
+		if err != nil {
+			return nil, fmt.Errorf("bundle: cannot parse Variant-Key header of %v: %v", u, err)
+		}
+	}
+	for _, key := range crossProduct(variants.sortedValues(header)) {
+		for i, vks := range variantKeys {
+			for _, vk := range vks {
+				if equalStrings(vk, key) {
+					return es[i], nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("bundle: no exchange for %v has a Variant-Key acceptable for the request", u)
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sortedValues returns, for each axis of v, the available values acceptable
+// for a request with header, most preferred first, followed by the first
+// available value as the default if it is not acceptable.
+func (v Variants) sortedValues(header http.Header) [][]string {
+	sorted := make([][]string, len(v))
+	for i, vals := range v {
+		name, available := vals[0], vals[1:]
+		sorted[i] = negotiate(name, header.Values(name), available)
+		if !containsString(sorted[i], available[0]) {
+			sorted[i] = append(sorted[i], available[0])
+		}
+	}
+	return sorted
+}
+
+// crossProduct returns all the combinations of one value of each list, in
+// order of the earlier lists first, e.g. [[a b] [c d]] gives
+// [[a c] [a d] [b c] [b d]].
+func crossProduct(lists [][]string) [][]string {
+	result := [][]string{{}}
+	for _, list := range lists {
+		var next [][]string
+		for _, prefix := range result {
+			for _, value := range list {
+				next = append(next, append(append([]string{}, prefix...), value))
+			}
+		}
+		result = next
+	}
+	return result
+}
+
+// negotiate returns the values in available that are acceptable for the
+// request header values of the given name, most preferred first. A request
+// without the header has no preference.
+func negotiate(name string, requestValues []string, available []string) []string {
+	if len(requestValues) == 0 {
+		return nil
+	}
+	prefs := parseWeightedList(requestValues)
+	switch http.CanonicalHeaderKey(name) {
+	case "Accept":
+		return sortByQuality(available, func(value string) float64 {
+			return mediaTypeQuality(prefs, value)
+		})
+	case "Accept-Encoding":
+		return sortByQuality(available, func(value string) float64 {
+			return encodingQuality(prefs, value)
+		})
+	case "Accept-Language":
+		return filterLanguages(prefs, available)
+	default:
+		var result []string
+		for _, p := range prefs {
+			for _, value := range available {
+				if strings.EqualFold(p.value, value) && p.q > 0 && !containsString(result, value) {
+					result = append(result, value)
+				}
+			}
+		}
+		return result
+	}
+}
+
+// weightedValue is an element of a header value like "en;q=0.8".
+type weightedValue struct {
+	value string
+	q     float64
+}
+
+// parseWeightedList parses comma-separated header values with optional "q"
+// parameters, e.g. "text/html, application/xml;q=0.9". Parameters other than
+// "q" are ignored. The result is sorted by q in descending order, keeping the
+// request order for equal q values.
+func parseWeightedList(values []string) []weightedValue {
+	var result []weightedValue
+	for _, v := range values {
+		for _, elem := range strings.Split(v, ",") {
+			params := strings.Split(elem, ";")
+			value := strings.ToLower(strings.TrimSpace(params[0]))
+			if value == "" {
+				continue
+			}
+			q := 1.0
+			for _, param := range params[1:] {
+				param = strings.TrimSpace(param)
+				if strings.HasPrefix(param, "q=") || strings.HasPrefix(param, "Q=") {
+					if f, err := strconv.ParseFloat(param[2:], 64); err == nil && 0 <= f && f <= 1 {
+						q = f
+					}
+				}
+			}
+			result = append(result, weightedValue{value, q})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].q > result[j].q })
+	return result
+}
+
+// sortByQuality returns the values in available with a positive quality,
+// sorted by quality in descending order.
+func sortByQuality(available []string, quality func(string) float64) []string {
+	type qualified struct {
+		value string
+		q     float64
+	}
+	var qs []qualified
+	for _, value := range available {
+		if q := quality(value); q > 0 {
+			qs = append(qs, qualified{value, q})
+		}
+	}
+	sort.SliceStable(qs, func(i, j int) bool { return qs[i].q > qs[j].q })
+	var result []string
+	for _, q := range qs {
+		result = append(result, q.value)
+	}
+	return result
+}
+
+// mediaTypeQuality returns the quality of mediaType for Accept preferences,
+// taken from the most specific matching media range.
+func mediaTypeQuality(prefs []weightedValue, mediaType string) float64 {
+	mediaType = strings.ToLower(mediaType)
+	slash := strings.Index(mediaType, "/")
+	if slash < 0 {
+		return 0
+	}
+	q, specificity := 0.0, -1
+	for _, p := range prefs {
+		s := -1
+		switch {
+		case p.value == mediaType:
+			s = 2
+		case p.value == mediaType[:slash]+"/*":
+			s = 1
+		case p.value == "*/*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = p.q, s
+		}
+	}
+	return q
+}
+
+// encodingQuality returns the quality of a content coding for
+// Accept-Encoding preferences. "identity" is acceptable unless excluded.
+func encodingQuality(prefs []weightedValue, coding string) float64 {
+	coding = strings.ToLower(coding)
+	q, found := 0.0, false
+	for _, p := range prefs {
+		if p.value == coding {
+			return p.q
+		}
+		if p.value == "*" && !found {
+			q, found = p.q, true
+		}
+	}
+	if !found && coding == "identity" {
+		return 1
+	}
+	return q
+}
+
+// filterLanguages returns the language tags in available that match the
+// Accept-Language preferences by basic filtering (RFC 4647 section 3.3.1),
+// in the order of the preferences.
+func filterLanguages(prefs []weightedValue, available []string) []string {
+	var result []string
+	for _, p := range prefs {
+		if p.q <= 0 {
+			continue
+		}
+		for _, tag := range available {
+			t := strings.ToLower(tag)
+			if p.value == "*" || t == p.value || strings.HasPrefix(t, p.value+"-") {
+				if !containsString(result, tag) {
+					result = append(result, tag)
+				}
+			}
+		}
+	}
+	return result
+}
+
+func containsString(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package bundle_test
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/WICG/webpackage/go/bundle"
+	"github.com/WICG/webpackage/go/bundle/version"
+)
+
+func TestLookup(t *testing.T) {
+	b := createTestBundleWithVariants(version.VersionB1)
+	for _, c := range []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{"", "en"},
+		{"ja", "ja"},
+		{"en, ja", "en"},
+		{"fr, ja;q=0.5, en;q=0.4", "ja"},
+		{"fr", "en"},
+		{"ja-JP", "en"},
+		{"EN-US, JA;q=0.9", "ja"},
+		{"*", "en"},
+		{"ja;q=0, *", "en"},
+	} {
+		header := http.Header{}
+		if c.acceptLanguage != "" {
+			header.Set("Accept-Language", c.acceptLanguage)
+		}
+		e, err := b.Lookup(urlMustParse("https://variants.example.com/"), header)
+		if err != nil {
+			t.Fatalf("Lookup unexpectedly failed: %v", err)
+		}
+		if got := e.Response.Header.Get("Variant-Key"); got != c.want {
+			t.Errorf("Accept-Language %q: got Variant-Key %q, want %q", c.acceptLanguage, got, c.want)
+		}
+	}
+
+	if _, err := b.Lookup(urlMustParse("https://variants.example.com/missing"), nil); err != ErrExchangeNotFound {
+		t.Errorf("Lookup for missing URL: got %v, want ErrExchangeNotFound", err)
+	}
+}
+
+func TestLookupMultipleAxes(t *testing.T) {
+	u := urlMustParse("https://variants.example.com/")
+	variants := "Accept;text/html;application/json, Accept-Encoding;gzip;br;identity"
+	b := &Bundle{Version: version.VersionB1}
+	for _, vk := range []string{"text/html;gzip", "text/html;br", "text/html;identity, application/json;identity", "application/json;gzip", "application/json;br"} {
+		b.Exchanges = append(b.Exchanges, &Exchange{
+			Request{URL: u},
+			Response{
+				Status: 200,
+				Header: http.Header{"Variants": []string{variants}, "Variant-Key": []string{vk}},
+			},
+		})
+	}
+
+	for _, c := range []struct {
+		accept         string
+		acceptEncoding string
+		want           string
+	}{
+		{"", "", "text/html;gzip"},
+		{"application/json", "br, gzip;q=0.5", "application/json;br"},
+		{"text/*;q=0.5, application/*;q=0.8", "gzip", "application/json;gzip"},
+		{"text/html", "deflate", "text/html;identity, application/json;identity"},
+		{"application/json", "*;q=0, br", "application/json;br"},
+		{"application/json, text/html;q=0", "identity;q=0, deflate", "application/json;gzip"},
+	} {
+		header := http.Header{}
+		if c.accept != "" {
+			header.Set("Accept", c.accept)
+		}
+		if c.acceptEncoding != "" {
+			header.Set("Accept-Encoding", c.acceptEncoding)
+		}
+		e, err := b.Lookup(u, header)
+		if err != nil {
+			t.Fatalf("Lookup unexpectedly failed: %v", err)
+		}
+		if got := e.Response.Header.Get("Variant-Key"); got != c.want {
+			t.Errorf("Accept %q, Accept-Encoding %q: got Variant-Key %q, want %q", c.accept, c.acceptEncoding, got, c.want)
+		}
+	}
+}
+
+func TestLookupMissingCombinations(t *testing.T) {
+	u := urlMustParse("https://variants.example.com/")
+	variants := "Accept-Language;en;ja, Accept-Encoding;gzip;identity"
+	b := &Bundle{Version: version.VersionB1}
+	for _, vk := range []string{"en;gzip", "en;identity", "ja;identity"} {
+		b.Exchanges = append(b.Exchanges, &Exchange{
+			Request{URL: u},
+			Response{
+				Status: 200,
+				Header: http.Header{"Variants": []string{variants}, "Variant-Key": []string{vk}},
+			},
+		})
+	}
+
+	for _, c := range []struct {
+		acceptLanguage string
+		acceptEncoding string
+		want           string
+	}{
+		{"", "", "en;gzip"},
+		{"ja", "gzip", "ja;identity"},
+		{"ja", "identity;q=0, gzip", "en;gzip"},
+		{"ja, en;q=0.5", "gzip", "ja;identity"},
+		// The default values are tried after the acceptable ones.
+		{"fr", "br", "en;identity"},
+		{"fr", "", "en;gzip"},
+		{"", "br, identity;q=0", "en;gzip"},
+	} {
+		header := http.Header{}
+		if c.acceptLanguage != "" {
+			header.Set("Accept-Language", c.acceptLanguage)
+		}
+		if c.acceptEncoding != "" {
+			header.Set("Accept-Encoding", c.acceptEncoding)
+		}
+		e, err := b.Lookup(u, header)
+		if err != nil {
+			t.Fatalf("Accept-Language %q, Accept-Encoding %q: Lookup unexpectedly failed: %v", c.acceptLanguage, c.acceptEncoding, err)
+		}
+		if got := e.Response.Header.Get("Variant-Key"); got != c.want {
+			t.Errorf("Accept-Language %q, Accept-Encoding %q: got Variant-Key %q, want %q", c.acceptLanguage, c.acceptEncoding, got, c.want)
+		}
+	}
+
+	// ja;gzip and en;gzip are the only acceptable combinations, and neither
+	// is stored.
+	b.Exchanges = b.Exchanges[2:]
+	header := http.Header{"Accept-Language": []string{"ja"}, "Accept-Encoding": []string{"gzip, identity;q=0"}}
+	if e, err := b.Lookup(u, header); err == nil {
+		t.Errorf("Lookup unexpectedly succeeded with Variant-Key %q", e.Response.Header.Get("Variant-Key"))
+	}
+}
@@ -3,6 +3,7 @@ package version
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 
 	"github.com/WICG/webpackage/go/signedexchange/mice"
@@ -44,17 +45,25 @@ func Parse(str string) (Version, bool) {
 	return "", false
 }
 
-func (v Version) HeaderMagicBytes() []byte {
+func unsupportedVersionError(v Version) error {
+	return fmt.Errorf("bundle: unsupported version %q", string(v))
+}
+
+func (v Version) HeaderMagicBytes() ([]byte, error) {
 	switch v {
 	case VersionB1:
-		return append(HeaderMagicBytesB1, VersionMagicBytesB1...)
+		return append(HeaderMagicBytesB1, VersionMagicBytesB1...), nil
 	case VersionB2:
-		return append(HeaderMagicBytesB2, VersionMagicBytesB2...)
+		return append(HeaderMagicBytesB2, VersionMagicBytesB2...), nil
 	default:
-		panic("not reached")
+		return nil, unsupportedVersionError(v)
 	}
 }
 
+// ErrUnknownVersion is returned by ParseMagicBytes when the header magic is
+// valid but the version is not one of AllVersions.
+var ErrUnknownVersion = errors.New("bundle: unrecognized version magic")
+
 func ParseMagicBytes(r io.Reader) (Version, error) {
 	hdrMagic := make([]byte, len(HeaderMagicBytesB1))
 	if _, err := io.ReadFull(r, hdrMagic); err != nil {
@@ -80,26 +89,29 @@ func ParseMagicBytes(r io.Reader) (Version, error) {
 		}
 		return VersionB2, nil
 	}
-	return "", errors.New("bundle: unrecognized version magic")
+	if verMagic[0] != VersionMagicBytesB1[0] {
+		return "", errors.New("bundle: version is not a 4-byte byte string")
+	}
+	return "", ErrUnknownVersion
 }
 
-func (v Version) MiceEncoding() mice.Encoding {
+func (v Version) MiceEncoding() (mice.Encoding, error) {
 	switch v {
 	case VersionB1, VersionB2:
-		return mice.Draft03Encoding
+		return mice.Draft03Encoding, nil
 	default:
-		panic("not reached")
+		return "", unsupportedVersionError(v)
 	}
 }
 
-func (v Version) SignatureContextString() string {
+func (v Version) SignatureContextString() (string, error) {
 	switch v {
 	case VersionB1:
-		return "Web Package 1 b1"
+		return "Web Package 1 b1", nil
 	case VersionB2:
-		return "Web Package 1 b2"
+		return "Web Package 1 b2", nil
 	default:
-		panic("not reached")
+		return "", unsupportedVersionError(v)
 	}
 }
 
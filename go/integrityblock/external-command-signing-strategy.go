@@ -0,0 +1,87 @@
+package integrityblock
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/WICG/webpackage/go/internal/signingalgorithm"
+)
+
+const (
+	// ExternalCommandPublicKeyArg is appended to the command to have it print
+	// the public key in PEM format to stdout.
+	ExternalCommandPublicKeyArg = "public-key"
+	// ExternalCommandSignArg is appended to the command to have it read the
+	// data to be signed from stdin and write the raw signature to stdout.
+	ExternalCommandSignArg = "sign"
+)
+
+// ExternalCommandSigningStrategy implementing `ISigningStrategy` delegates signing to an
+// external program, e.g. one talking to an HSM, so that the private key never has to be on
+// the machine building the web bundle. The program is run with an extra argument:
+//
+//   - `public-key`: it must print the Ed25519 or ECDSA P-256 public key in PEM format.
+//   - `sign`: it must read the data to be signed from stdin and print the signature, i.e. the
+//     raw 64-byte Ed25519 signature or the ASN.1 DER-encoded ECDSA P-256 SHA-256 signature.
+//
+// The program's stderr is passed through, and a non-zero exit status fails the signing.
+type ExternalCommandSigningStrategy struct {
+	program   string
+	args      []string
+	publicKey crypto.PublicKey
+}
+
+// NewExternalCommandSigningStrategy creates a signing strategy running program with args,
+// which are passed as they are, e.g. without splitting them at whitespace.
+func NewExternalCommandSigningStrategy(program string, args ...string) (*ExternalCommandSigningStrategy, error) {
+	if program == "" {
+		return nil, errors.New("integrityblock: The signing command is empty.")
+	}
+	return &ExternalCommandSigningStrategy{program: program, args: args}, nil
+}
+
+// run runs the command with arg appended, feeding it stdin, and returns its stdout.
+func (bss *ExternalCommandSigningStrategy) run(arg string, stdin []byte) ([]byte, error) {
+	var stdout bytes.Buffer
+	args := append(append([]string{}, bss.args...), arg)
+	cmd := exec.Command(bss.program, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("integrityblock: The signing command failed to %s: %v", arg, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+func (bss *ExternalCommandSigningStrategy) Sign(data []byte) ([]byte, error) {
+	signature, err := bss.run(ExternalCommandSignArg, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(signature) == 0 {
+		return nil, errors.New("integrityblock: The signing command printed no signature.")
+	}
+	return signature, nil
+}
+
+// GetPublicKey returns the public key printed by the command. The command is only run once.
+func (bss *ExternalCommandSigningStrategy) GetPublicKey() (crypto.PublicKey, error) {
+	if bss.publicKey != nil {
+		return bss.publicKey, nil
+	}
+	text, err := bss.run(ExternalCommandPublicKeyArg, nil)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := signingalgorithm.ParsePublicKey(text)
+	if err != nil {
+		return nil, fmt.Errorf("integrityblock: Failed to parse the public key printed by the signing command: %v", err)
+	}
+	bss.publicKey = publicKey
+	return publicKey, nil
+}
@@ -0,0 +1,143 @@
+package integrityblock
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/WICG/webpackage/go/internal/cbor"
+)
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+// ParseIntegrityBlock parses the integrity block at the beginning of r. The
+// second return value is the length of the integrity block in bytes, i.e.
+// the offset of the web bundle that follows it.
+func ParseIntegrityBlock(r io.Reader) (*IntegrityBlock, int64, error) {
+	cr := &countingReader{r: r}
+	dec := cbor.NewDecoder(cr)
+
+	n, err := dec.DecodeArrayHeader()
+	if err != nil {
+		return nil, 0, fmt.Errorf("integrityblock: Failed to decode the integrity block header: %v", err)
+	}
+	if n != 3 && n != 4 {
+		return nil, 0, fmt.Errorf("integrityblock: The integrity block must be an array of 3 or 4 items, got %d", n)
+	}
+	magic, err := dec.DecodeByteString()
+	if err != nil {
+		return nil, 0, fmt.Errorf("integrityblock: Failed to decode the magic: %v", err)
+	}
+	if !bytes.Equal(magic, IntegrityBlockMagic) {
+		return nil, 0, errors.New("integrityblock: Wrong magic bytes.")
+	}
+	version, err := dec.DecodeByteString()
+	if err != nil {
+		return nil, 0, fmt.Errorf("integrityblock: Failed to decode the version: %v", err)
+	}
+	ib := &IntegrityBlock{Magic: magic, Version: version}
+	switch {
+	case bytes.Equal(version, VersionB1):
+		if n != 3 {
+			return nil, 0, fmt.Errorf("integrityblock: An integrity block of version b1 must be an array of 3 items, got %d", n)
+		}
+	case bytes.Equal(version, VersionB2):
+		if n != 4 {
+			return nil, 0, fmt.Errorf("integrityblock: An integrity block of version 2 must be an array of 4 items, got %d", n)
+		}
+		if ib.Attributes, err = parseIntegrityBlockAttributes(dec); err != nil {
+			return nil, 0, fmt.Errorf("integrityblock: %v", err)
+		}
+	default:
+		return nil, 0, fmt.Errorf("integrityblock: Unsupported version %q.", version)
+	}
+
+	n, err = dec.DecodeArrayHeader()
+	if err != nil {
+		return nil, 0, fmt.Errorf("integrityblock: Failed to decode the signature stack: %v", err)
+	}
+	if n == 0 {
+		return nil, 0, errors.New("integrityblock: The signature stack is empty.")
+	}
+	for i := uint64(0); i < n; i++ {
+		is, err := parseIntegritySignature(dec)
+		if err != nil {
+			return nil, 0, fmt.Errorf("integrityblock: Signature #%d: %v", i, err)
+		}
+		ib.SignatureStack = append(ib.SignatureStack, is)
+	}
+	return ib, cr.n, nil
+}
+
+func parseIntegrityBlockAttributes(dec *cbor.Decoder) (IntegrityBlockAttributesMap, error) {
+	n, err := dec.DecodeMapHeader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the integrity block attributes: %v", err)
+	}
+	attributes := make(IntegrityBlockAttributesMap)
+	for i := uint64(0); i < n; i++ {
+		key, err := dec.DecodeTextString()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode an integrity block attribute name: %v", err)
+		}
+		if _, exists := attributes[key]; exists {
+			return nil, fmt.Errorf("duplicated integrity block attribute %q", key)
+		}
+		value, err := dec.DecodeTextString()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the integrity block attribute %q: %v", key, err)
+		}
+		attributes[key] = value
+	}
+	if attributes[WebBundleIdAttributeName] == "" {
+		return nil, fmt.Errorf("the integrity block attribute %q is missing", WebBundleIdAttributeName)
+	}
+	return attributes, nil
+}
+
+func parseIntegritySignature(dec *cbor.Decoder) (*IntegritySignature, error) {
+	n, err := dec.DecodeArrayHeader()
+	if err != nil {
+		return nil, err
+	}
+	if n != 2 {
+		return nil, fmt.Errorf("an integrity signature must be an array of 2 items, got %d", n)
+	}
+
+	n, err = dec.DecodeMapHeader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the signature attributes: %v", err)
+	}
+	attributes := make(SignatureAttributesMap)
+	for i := uint64(0); i < n; i++ {
+		key, err := dec.DecodeTextString()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode a signature attribute name: %v", err)
+		}
+		if _, exists := attributes[key]; exists {
+			return nil, fmt.Errorf("duplicated signature attribute %q", key)
+		}
+		value, err := dec.DecodeByteString()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the signature attribute %q: %v", key, err)
+		}
+		attributes[key] = value
+	}
+
+	signature, err := dec.DecodeByteString()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the signature: %v", err)
+	}
+	return &IntegritySignature{SignatureAttributes: attributes, Signature: signature}, nil
+}
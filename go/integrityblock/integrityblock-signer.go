@@ -1,8 +1,12 @@
 package integrityblock
 
 import (
+	"crypto"
+	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/sha256"
 	"errors"
+	"fmt"
 
 	"github.com/WICG/webpackage/go/internal/cbor"
 )
@@ -22,10 +26,35 @@ func VerifyEd25519Signature(publicKey ed25519.PublicKey, signature, dataToBeSign
 	return signatureOk, nil
 }
 
+// VerifyEcdsaP256SHA256Signature verifies that the given ASN.1 DER-encoded signature of the SHA-256 hash of the data signed
+// can be verified with the given public key.
+func VerifyEcdsaP256SHA256Signature(publicKey *ecdsa.PublicKey, signature, dataToBeSigned []byte) (bool, error) {
+	hash := sha256.Sum256(dataToBeSigned)
+	signatureOk := ecdsa.VerifyASN1(publicKey, hash[:], signature)
+	if !signatureOk {
+		return signatureOk, errors.New("integrityblock: Signature verification failed.")
+	}
+	return signatureOk, nil
+}
+
+// verifySignature verifies the signature with the algorithm matching the type of the public key.
+func verifySignature(publicKey crypto.PublicKey, signature, dataToBeSigned []byte) error {
+	var err error
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		_, err = VerifyEd25519Signature(key, signature, dataToBeSigned)
+	case *ecdsa.PublicKey:
+		_, err = VerifyEcdsaP256SHA256Signature(key, signature, dataToBeSigned)
+	default:
+		err = fmt.Errorf("integrityblock: Unsupported public key type: %T", publicKey)
+	}
+	return err
+}
+
 // SignAndAddNewSignature contains the main logic for generating the new signature and
 // prepending the integrity block's signature stack with a new integrity signature object.
-func (ibs *IntegrityBlockSigner) SignAndAddNewSignature(ed25519publicKey ed25519.PublicKey, signatureAttributes SignatureAttributesMap) error {
-	integrityBlockBytes, err := ibs.IntegrityBlock.CborBytes()
+func (ibs *IntegrityBlockSigner) SignAndAddNewSignature(publicKey crypto.PublicKey, signatureAttributes SignatureAttributesMap) error {
+	integrityBlockBytes, err := ibs.IntegrityBlock.signedIntegrityBlock(ibs.IntegrityBlock.SignatureStack).CborBytes()
 	if err != nil {
 		return err
 	}
@@ -48,7 +77,9 @@ func (ibs *IntegrityBlockSigner) SignAndAddNewSignature(ed25519publicKey ed25519
 
 	// Verification is done after signing to ensure that the signing was successful and that the obtained public key
 	// is not corrupted and corresponds to the private key used for signing.
-	VerifyEd25519Signature(ed25519publicKey, signature, dataToBeSigned)
+	if err := verifySignature(publicKey, signature, dataToBeSigned); err != nil {
+		return err
+	}
 
 	ibs.IntegrityBlock.addNewSignatureToIntegrityBlock(signatureAttributes, signature)
 	return nil
@@ -0,0 +1,154 @@
+package integrityblock
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/WICG/webpackage/go/integrityblock/webbundleid"
+)
+
+// VerificationResult is the result of a successful Verify.
+type VerificationResult struct {
+	// PublicKeys holds the public keys of the signatures, in the order of the
+	// signature stack.
+	PublicKeys []crypto.PublicKey
+	// WebBundleId is the Web Bundle ID of the signed web bundle. It is declared
+	// in the attributes of a VersionB2 integrity block, and derived from the
+	// public key of the first signature in the stack for VersionB1.
+	WebBundleId string
+}
+
+// Ed25519PublicKey returns the public key stored in the signature attributes.
+func (is *IntegritySignature) Ed25519PublicKey() (ed25519.PublicKey, error) {
+	key, ok := is.SignatureAttributes[Ed25519publicKeyAttributeName]
+	if !ok {
+		return nil, fmt.Errorf("integrityblock: Signature attribute %q is missing.", Ed25519publicKeyAttributeName)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("integrityblock: Ed25519 public key must be %d bytes, got %d.", ed25519.PublicKeySize, len(key))
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// EcdsaP256PublicKey returns the ECDSA P-256 public key stored in compressed form in the signature attributes.
+func (is *IntegritySignature) EcdsaP256PublicKey() (*ecdsa.PublicKey, error) {
+	key, ok := is.SignatureAttributes[EcdsaP256SHA256PublicKeyAttributeName]
+	if !ok {
+		return nil, fmt.Errorf("integrityblock: Signature attribute %q is missing.", EcdsaP256SHA256PublicKeyAttributeName)
+	}
+	x, y := elliptic.UnmarshalCompressed(elliptic.P256(), key)
+	if x == nil {
+		return nil, errors.New("integrityblock: ECDSA P-256 public key must be a valid 33-byte compressed point.")
+	}
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+}
+
+// PublicKey returns the public key stored in the signature attributes, either an ed25519.PublicKey or an
+// *ecdsa.PublicKey. The attributes must contain exactly one public key.
+func (is *IntegritySignature) PublicKey() (crypto.PublicKey, error) {
+	_, hasEd25519 := is.SignatureAttributes[Ed25519publicKeyAttributeName]
+	_, hasEcdsaP256 := is.SignatureAttributes[EcdsaP256SHA256PublicKeyAttributeName]
+	switch {
+	case hasEd25519 && hasEcdsaP256:
+		return nil, errors.New("integrityblock: Signature attributes must contain exactly one public key.")
+	case hasEd25519:
+		return is.Ed25519PublicKey()
+	case hasEcdsaP256:
+		return is.EcdsaP256PublicKey()
+	default:
+		return nil, fmt.Errorf("integrityblock: Signature attributes have neither %q nor %q.", Ed25519publicKeyAttributeName, EcdsaP256SHA256PublicKeyAttributeName)
+	}
+}
+
+// VerifySignature verifies the i-th signature of the signature stack against
+// webBundleHash, the SHA-512 hash of the web bundle. New signatures are
+// prepended to the stack, so in VersionB1 the i-th signature was computed over
+// the integrity block holding only the signatures after it. In VersionB2 every
+// signature was computed over the integrity block without signatures.
+func (ib *IntegrityBlock) VerifySignature(i int, webBundleHash []byte) error {
+	if i < 0 || i >= len(ib.SignatureStack) {
+		return errors.New("integrityblock: Signature index out of range.")
+	}
+	is := ib.SignatureStack[i]
+	publicKey, err := is.PublicKey()
+	if err != nil {
+		return err
+	}
+
+	integrityBlockBytes, err := ib.signedIntegrityBlock(ib.SignatureStack[i+1:]).CborBytes()
+	if err != nil {
+		return err
+	}
+	dataToBeSigned, err := GenerateDataToBeSigned(webBundleHash, integrityBlockBytes, is.SignatureAttributes)
+	if err != nil {
+		return err
+	}
+	return verifySignature(publicKey, is.Signature, dataToBeSigned)
+}
+
+// Verify verifies a web bundle signed with an integrity block, read from
+// bundleFile. It parses the integrity block, computes the SHA-512 hash of the
+// web bundle following it, and verifies every signature of the signature
+// stack against it as described in the
+// [Integrity Block Explainer](https://github.com/WICG/webpackage/blob/main/explainers/integrity-signature.md).
+// It fails if any of the signatures does not verify, or if the Web Bundle ID
+// declared by a VersionB2 integrity block can't be derived from the public key
+// of any of the signatures.
+func Verify(bundleFile io.ReadSeeker) (*VerificationResult, error) {
+	return VerifyWithTrustedPublicKey(bundleFile, nil)
+}
+
+// VerifyWithTrustedPublicKey is like Verify, but if trustedPublicKey is not
+// nil, the identity of the web bundle is established by trustedPublicKey
+// instead: one of the signatures must be made with it, and the declared Web
+// Bundle ID doesn't have to match any of the signatures. This lets embedders
+// that know the key of a Web Bundle ID accept bundles signed after a key
+// rotation.
+func VerifyWithTrustedPublicKey(bundleFile io.ReadSeeker, trustedPublicKey crypto.PublicKey) (*VerificationResult, error) {
+	if _, err := bundleFile.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	ib, offset, err := ParseIntegrityBlock(bundleFile)
+	if err != nil {
+		return nil, err
+	}
+	webBundleHash, err := ComputeWebBundleSha512(bundleFile, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &VerificationResult{}
+	for i, is := range ib.SignatureStack {
+		if err := ib.VerifySignature(i, webBundleHash); err != nil {
+			return nil, fmt.Errorf("%v (signature #%d)", err, i)
+		}
+		// VerifySignature has checked the public key.
+		publicKey, _ := is.PublicKey()
+		result.PublicKeys = append(result.PublicKeys, publicKey)
+	}
+
+	if !ib.IsVersionB2() {
+		if result.WebBundleId, err = webbundleid.GetWebBundleId(result.PublicKeys[0]); err != nil {
+			return nil, err
+		}
+	} else {
+		result.WebBundleId = ib.WebBundleId()
+	}
+	if trustedPublicKey != nil {
+		if !ib.HasSignatureWithPublicKey(trustedPublicKey) {
+			return nil, errors.New("integrityblock: None of the signatures is made with the trusted public key.")
+		}
+		return result, nil
+	}
+	for _, publicKey := range result.PublicKeys {
+		if webBundleId, err := webbundleid.GetWebBundleId(publicKey); err == nil && webBundleId == result.WebBundleId {
+			return result, nil
+		}
+	}
+	return nil, fmt.Errorf("integrityblock: The Web Bundle ID %s doesn't match any of the signatures.", result.WebBundleId)
+}
@@ -2,7 +2,10 @@ package integrityblock
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/sha512"
 	"encoding/binary"
 	"errors"
@@ -16,6 +19,10 @@ import (
 
 type SignatureAttributesMap map[string][]byte
 
+// IntegrityBlockAttributesMap holds the attributes of the integrity block itself, which only
+// exist in VersionB2.
+type IntegrityBlockAttributesMap map[string]string
+
 type IntegritySignature struct {
 	SignatureAttributes SignatureAttributesMap
 	Signature           []byte
@@ -24,11 +31,14 @@ type IntegritySignature struct {
 type IntegrityBlock struct {
 	Magic          []byte
 	Version        []byte
+	Attributes     IntegrityBlockAttributesMap
 	SignatureStack []*IntegritySignature
 }
 
 const (
-	Ed25519publicKeyAttributeName = "ed25519PublicKey"
+	Ed25519publicKeyAttributeName         = "ed25519PublicKey"
+	EcdsaP256SHA256PublicKeyAttributeName = "ecdsaP256SHA256PublicKey"
+	WebBundleIdAttributeName              = "webBundleId"
 )
 
 var IntegrityBlockMagic = []byte{0xf0, 0x9f, 0x96, 0x8b, 0xf0, 0x9f, 0x93, 0xa6}
@@ -36,6 +46,9 @@ var IntegrityBlockMagic = []byte{0xf0, 0x9f, 0x96, 0x8b, 0xf0, 0x9f, 0x93, 0xa6}
 // "b1" as bytes and 2 empty bytes
 var VersionB1 = []byte{0x31, 0x62, 0x00, 0x00}
 
+// "2" as bytes and 3 empty bytes. Integrity blocks of this version have attributes declaring the Web Bundle ID.
+var VersionB2 = []byte{0x32, 0x00, 0x00, 0x00}
+
 // cborBytes writes the signature attributes map as CBOR using the given encoder so that the map's key is text string and value byte string.
 func (sa SignatureAttributesMap) cborBytes(enc *cbor.Encoder) error {
 	mes := []*cbor.MapEntryEncoder{}
@@ -52,6 +65,22 @@ func (sa SignatureAttributesMap) cborBytes(enc *cbor.Encoder) error {
 	return nil
 }
 
+// cborBytes writes the integrity block attributes map as CBOR using the given encoder so that both the map's key and value are text strings.
+func (ia IntegrityBlockAttributesMap) cborBytes(enc *cbor.Encoder) error {
+	mes := []*cbor.MapEntryEncoder{}
+	for key, value := range ia {
+		mes = append(mes,
+			cbor.GenerateMapEntry(func(keyE *cbor.Encoder, valueE *cbor.Encoder) {
+				keyE.EncodeTextString(key)
+				valueE.EncodeTextString(value)
+			}))
+	}
+	if err := enc.EncodeMap(mes); err != nil {
+		return fmt.Errorf("integrityblock: Failed to encode integrity block attributes: %v", err)
+	}
+	return nil
+}
+
 // cborBytes writes the integrity signature as CBOR using the given encoder containing the signature attributes and the signature.
 func (is *IntegritySignature) cborBytes(enc *cbor.Encoder) error {
 	enc.EncodeArrayHeader(2)
@@ -71,7 +100,11 @@ func (ib *IntegrityBlock) CborBytes() ([]byte, error) {
 	var buf bytes.Buffer
 	enc := cbor.NewEncoder(&buf)
 
-	err := enc.EncodeArrayHeader(3)
+	numItems := 3
+	if ib.IsVersionB2() {
+		numItems = 4
+	}
+	err := enc.EncodeArrayHeader(numItems)
 	if err != nil {
 		return nil, err
 	}
@@ -86,6 +119,12 @@ func (ib *IntegrityBlock) CborBytes() ([]byte, error) {
 		return nil, err
 	}
 
+	if ib.IsVersionB2() {
+		if err := ib.Attributes.cborBytes(enc); err != nil {
+			return nil, err
+		}
+	}
+
 	err = enc.EncodeArrayHeader(len(ib.SignatureStack))
 	for _, integritySignature := range ib.SignatureStack {
 		if err := integritySignature.cborBytes(enc); err != nil {
@@ -96,6 +135,51 @@ func (ib *IntegrityBlock) CborBytes() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// IsVersionB2 reports whether the integrity block is of VersionB2, i.e. has attributes.
+func (ib *IntegrityBlock) IsVersionB2() bool {
+	return bytes.Equal(ib.Version, VersionB2)
+}
+
+// WebBundleId returns the Web Bundle ID declared in the attributes of a VersionB2 integrity block, or an
+// empty string for VersionB1, where the Web Bundle ID is derived from the public key of the first signature.
+func (ib *IntegrityBlock) WebBundleId() string {
+	return ib.Attributes[WebBundleIdAttributeName]
+}
+
+// SetWebBundleId makes the integrity block a VersionB2 one declaring webBundleId as the Web Bundle ID.
+// The existing signatures would become invalid, so it fails unless the signature stack is empty or the
+// integrity block already declares the same Web Bundle ID.
+func (ib *IntegrityBlock) SetWebBundleId(webBundleId string) error {
+	if ib.IsVersionB2() && ib.WebBundleId() == webBundleId {
+		return nil
+	}
+	if len(ib.SignatureStack) != 0 {
+		if ib.IsVersionB2() {
+			return fmt.Errorf("integrityblock: The integrity block already declares the Web Bundle ID %s.", ib.WebBundleId())
+		}
+		return errors.New("integrityblock: Cannot set the Web Bundle ID of an integrity block with signatures of version b1.")
+	}
+	ib.Version = VersionB2
+	ib.Attributes = IntegrityBlockAttributesMap{WebBundleIdAttributeName: webBundleId}
+	return nil
+}
+
+// signedIntegrityBlock returns the integrity block that a signature on top of the signatures in the
+// given stack signs over. In VersionB1 it contains the stack, so that each signature also signs the
+// signatures below it. In VersionB2 all the signatures sign over the integrity block with an empty
+// signature stack, since the attributes alone define the identity of the web bundle.
+func (ib *IntegrityBlock) signedIntegrityBlock(stack []*IntegritySignature) *IntegrityBlock {
+	if ib.IsVersionB2() {
+		stack = nil
+	}
+	return &IntegrityBlock{
+		Magic:          ib.Magic,
+		Version:        ib.Version,
+		Attributes:     ib.Attributes,
+		SignatureStack: stack,
+	}
+}
+
 // generateEmptyIntegrityBlock creates an empty integrity block which does not have any integrity signatures in the signature stack yet.
 func generateEmptyIntegrityBlock() *IntegrityBlock {
 	var integritySignatures []*IntegritySignature
@@ -126,11 +210,10 @@ func readWebBundlePayloadLength(bundleFile *os.File) (int64, error) {
 	return int64(binary.BigEndian.Uint64(webBundleLengthBytes)), nil
 }
 
-// obtainIntegrityBlock returns either the existing integrity block parsed (not supported in v1) or a newly
-// created empty integrity block. Integrity block preceeds the actual web bundle bytes. The second return
-// value marks the offset from which point onwards we need to copy the web bundle bytes from. It will be
-// needed later in the signing process (TODO) because we cannot rely on the integrity block length, because
-// we don't know if the integrity block already existed or not.
+// ObtainIntegrityBlock returns either the existing integrity block parsed or a newly created empty integrity
+// block. Integrity block preceeds the actual web bundle bytes. The second return value marks the offset from
+// which point onwards we need to copy the web bundle bytes from, i.e. the length of the existing integrity
+// block, or 0 if there is none.
 func ObtainIntegrityBlock(bundleFile *os.File) (*IntegrityBlock, int64, error) {
 	webBundleLen, err := readWebBundlePayloadLength(bundleFile)
 	if err != nil {
@@ -147,14 +230,45 @@ func ObtainIntegrityBlock(bundleFile *os.File) (*IntegrityBlock, int64, error) {
 	}
 
 	if integrityBlockLen != 0 {
-		// Read existing integrity block. Not supported in v1.
-		return nil, integrityBlockLen, errors.New("Web bundle already contains an integrity block. Please provide an unsigned web bundle.")
+		if _, err := bundleFile.Seek(0, io.SeekStart); err != nil {
+			return nil, 0, err
+		}
+		integrityBlock, parsedLen, err := ParseIntegrityBlock(bundleFile)
+		if err != nil {
+			return nil, 0, err
+		}
+		if parsedLen != integrityBlockLen {
+			return nil, 0, fmt.Errorf("integrityblock: The integrity block is %d bytes, but the web bundle length implies %d bytes.", parsedLen, integrityBlockLen)
+		}
+		return integrityBlock, integrityBlockLen, nil
 	}
 
 	integrityBlock := generateEmptyIntegrityBlock()
 	return integrityBlock, integrityBlockLen, nil
 }
 
+// HasSignatureWithPublicKey reports whether a signature in the signature stack was made with the key
+// whose public key is publicKey.
+func (integrityBlock *IntegrityBlock) HasSignatureWithPublicKey(publicKey crypto.PublicKey) bool {
+	for _, is := range integrityBlock.SignatureStack {
+		signatureKey, err := is.PublicKey()
+		if err != nil {
+			continue
+		}
+		// Both ed25519.PublicKey and *ecdsa.PublicKey implement Equal.
+		if k, ok := signatureKey.(interface{ Equal(crypto.PublicKey) bool }); ok && k.Equal(publicKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveSignatures empties the signature stack, e.g. to sign the web bundle again after a key rotation.
+// Only the whole stack can be removed, since in VersionB1 each signature also signs the signatures below it.
+func (integrityBlock *IntegrityBlock) RemoveSignatures() {
+	integrityBlock.SignatureStack = nil
+}
+
 func (integrityBlock *IntegrityBlock) addNewSignatureToIntegrityBlock(signatureAttributes SignatureAttributesMap, signature []byte) {
 	is := []*IntegritySignature{{
 		SignatureAttributes: signatureAttributes,
@@ -201,9 +315,20 @@ func GenerateDataToBeSigned(webBundleHash, integrityBlockBytes []byte, signature
 	return buf.Bytes(), nil
 }
 
-// GenerateSignatureAttributesWithPublicKey generates the basis for the map for signature attributes containing the public key.
-func GenerateSignatureAttributesWithPublicKey(ed25519publicKey ed25519.PublicKey) SignatureAttributesMap {
-	return SignatureAttributesMap{Ed25519publicKeyAttributeName: []byte(ed25519publicKey)}
+// GenerateSignatureAttributesWithPublicKey generates the basis for the map for signature attributes containing the public key,
+// which is either an ed25519 public key or an ECDSA P-256 public key. The latter is stored in its compressed 33-byte form.
+func GenerateSignatureAttributesWithPublicKey(publicKey crypto.PublicKey) (SignatureAttributesMap, error) {
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		return SignatureAttributesMap{Ed25519publicKeyAttributeName: []byte(key)}, nil
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return nil, errors.New("integrityblock: ECDSA public key must be on the P-256 curve.")
+		}
+		return SignatureAttributesMap{EcdsaP256SHA256PublicKeyAttributeName: elliptic.MarshalCompressed(key.Curve, key.X, key.Y)}, nil
+	default:
+		return nil, fmt.Errorf("integrityblock: Unsupported public key type: %T", publicKey)
+	}
 }
 
 // WebBundleHasIntegrityBlock is a helper function that can be called with any file path to check if it has
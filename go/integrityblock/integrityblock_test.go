@@ -2,16 +2,34 @@ package integrityblock
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/x509"
 	"encoding/hex"
+	"encoding/pem"
+	"io"
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
+	"github.com/WICG/webpackage/go/integrityblock/webbundleid"
 	"github.com/WICG/webpackage/go/internal/cbor"
 	"github.com/WICG/webpackage/go/internal/testhelper"
 )
 
+func mustGenerateSignatureAttributes(t *testing.T, publicKey crypto.PublicKey) SignatureAttributesMap {
+	t.Helper()
+	signatureAttributes, err := GenerateSignatureAttributesWithPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signatureAttributes
+}
+
 func TestEmptyIntegrityBlock(t *testing.T) {
 	integrityBlock := generateEmptyIntegrityBlock()
 
@@ -233,7 +251,7 @@ func TestSignAndAddNewSignature(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = ibs.SignAndAddNewSignature(publicKey, GenerateSignatureAttributesWithPublicKey(publicKey))
+	err = ibs.SignAndAddNewSignature(publicKey, mustGenerateSignatureAttributes(t, publicKey))
 
 	integrityBlockBytes, err := ibs.IntegrityBlock.CborBytes()
 	if err != nil {
@@ -291,13 +309,13 @@ func TestSignAndAddNewSignatureWithExistingSignature(t *testing.T) {
 		t.Fatal(err)
 	}
 	// Public key from ISigningStrategy should be the same one as the one created in the beginning.
-	bytes.Equal([]byte(publicKey), []byte(pub))
+	bytes.Equal([]byte(publicKey.(ed25519.PublicKey)), []byte(pub))
 
-	signatureAttributesWithAdditionalAttribute := GenerateSignatureAttributesWithPublicKey(pub)
+	signatureAttributesWithAdditionalAttribute := mustGenerateSignatureAttributes(t, pub)
 	signatureAttributesWithAdditionalAttribute["hello"] = []byte("world")
 
 	err = ibs.SignAndAddNewSignature(pub, signatureAttributesWithAdditionalAttribute)
-	err = ibs.SignAndAddNewSignature(pub, GenerateSignatureAttributesWithPublicKey(pub))
+	err = ibs.SignAndAddNewSignature(pub, mustGenerateSignatureAttributes(t, pub))
 
 	integrityBlockBytes, err := ibs.IntegrityBlock.CborBytes()
 	if err != nil {
@@ -346,3 +364,526 @@ func bytesToCborAndToReadableStringHelper(bts []byte) (string, error) {
 	}
 	return cborAsString, nil
 }
+
+func TestParseAndVerifyIntegrityBlock(t *testing.T) {
+	_, priv1, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, priv2, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	webBundleHash := bytes.Repeat([]byte{0x42}, 64)
+
+	ibs := IntegrityBlockSigner{
+		WebBundleHash:  webBundleHash,
+		IntegrityBlock: generateEmptyIntegrityBlock(),
+	}
+	for _, priv := range []ed25519.PrivateKey{priv1, priv2} {
+		ibs.SigningStrategy = NewParsedEd25519KeySigningStrategy(priv)
+		pub := priv.Public().(ed25519.PublicKey)
+		if err := ibs.SignAndAddNewSignature(pub, mustGenerateSignatureAttributes(t, pub)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	integrityBlockBytes, err := ibs.IntegrityBlock.CborBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := bytes.NewReader(append(integrityBlockBytes, []byte("web bundle")...))
+	ib, offset, err := ParseIntegrityBlock(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset != int64(len(integrityBlockBytes)) {
+		t.Errorf("offset: got %d, want %d", offset, len(integrityBlockBytes))
+	}
+	if !reflect.DeepEqual(ib, ibs.IntegrityBlock) {
+		t.Errorf("got %v, want %v", ib, ibs.IntegrityBlock)
+	}
+
+	for i, priv := range []ed25519.PrivateKey{priv2, priv1} {
+		pub, err := ib.SignatureStack[i].Ed25519PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pub.Equal(priv.Public()) {
+			t.Errorf("signature #%d: wrong public key", i)
+		}
+		if err := ib.VerifySignature(i, webBundleHash); err != nil {
+			t.Errorf("signature #%d: %v", i, err)
+		}
+		if err := ib.VerifySignature(i, bytes.Repeat([]byte{0x43}, 64)); err == nil {
+			t.Errorf("signature #%d: verification with a wrong hash unexpectedly succeeded", i)
+		}
+	}
+}
+
+func TestParseIntegrityBlockErrors(t *testing.T) {
+	empty, err := generateEmptyIntegrityBlock().CborBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ParseIntegrityBlock(bytes.NewReader(empty)); err == nil {
+		t.Error("ParseIntegrityBlock of an empty signature stack unexpectedly succeeded")
+	}
+
+	ib := generateEmptyIntegrityBlock()
+	ib.addNewSignatureToIntegrityBlock(SignatureAttributesMap{Ed25519publicKeyAttributeName: []byte("publickey")}, []byte("signature"))
+	ibBytes, err := ib.CborBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ParseIntegrityBlock(bytes.NewReader(ibBytes[:len(ibBytes)-1])); err == nil {
+		t.Error("ParseIntegrityBlock of a truncated integrity block unexpectedly succeeded")
+	}
+
+	bundleFile, err := os.Open("./testfile.wbn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bundleFile.Close()
+	if _, _, err := ParseIntegrityBlock(bundleFile); err == nil {
+		t.Error("ParseIntegrityBlock of an unsigned web bundle unexpectedly succeeded")
+	}
+}
+
+func TestObtainExistingIntegrityBlock(t *testing.T) {
+	webBundle, err := os.ReadFile("./testfile.wbn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	integrityBlock := generateEmptyIntegrityBlock()
+	integrityBlock.addNewSignatureToIntegrityBlock(mustGenerateSignatureAttributes(t, pub), []byte("signature"))
+	integrityBlockBytes, err := integrityBlock.CborBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "signed.wbn")
+	if err := os.WriteFile(path, append(integrityBlockBytes, webBundle...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	bundleFile, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bundleFile.Close()
+
+	got, offset, err := ObtainIntegrityBlock(bundleFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset != int64(len(integrityBlockBytes)) {
+		t.Errorf("offset: got %d, want %d", offset, len(integrityBlockBytes))
+	}
+	if !reflect.DeepEqual(got, integrityBlock) {
+		t.Errorf("got %v, want %v", got, integrityBlock)
+	}
+	if !got.HasSignatureWithPublicKey(pub) {
+		t.Error("HasSignatureWithPublicKey: got false, want true")
+	}
+
+	got.RemoveSignatures()
+	if got.HasSignatureWithPublicKey(pub) {
+		t.Error("HasSignatureWithPublicKey after RemoveSignatures: got true, want false")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	webBundle, err := os.ReadFile("./testfile.wbn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	webBundleHash, err := ComputeWebBundleSha512(bytes.NewReader(webBundle), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ibs := IntegrityBlockSigner{
+		WebBundleHash:  webBundleHash,
+		IntegrityBlock: generateEmptyIntegrityBlock(),
+	}
+	var publicKeys []crypto.PublicKey
+	for i := 0; i < 2; i++ {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ibs.SigningStrategy = NewParsedEd25519KeySigningStrategy(priv)
+		if err := ibs.SignAndAddNewSignature(pub, mustGenerateSignatureAttributes(t, pub)); err != nil {
+			t.Fatal(err)
+		}
+		publicKeys = append([]crypto.PublicKey{pub}, publicKeys...)
+	}
+	integrityBlockBytes, err := ibs.IntegrityBlock.CborBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := append(integrityBlockBytes, webBundle...)
+
+	result, err := Verify(bytes.NewReader(signed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.PublicKeys, publicKeys) {
+		t.Errorf("PublicKeys: got %v, want %v", result.PublicKeys, publicKeys)
+	}
+	want, err := webbundleid.GetWebBundleId(publicKeys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.WebBundleId != want {
+		t.Errorf("WebBundleId: got %q, want %q", result.WebBundleId, want)
+	}
+
+	signed[len(signed)-10] ^= 1
+	if _, err := Verify(bytes.NewReader(signed)); err == nil {
+		t.Error("Verify of a modified web bundle unexpectedly succeeded")
+	}
+	if _, err := Verify(bytes.NewReader(webBundle)); err == nil {
+		t.Error("Verify of an unsigned web bundle unexpectedly succeeded")
+	}
+}
+
+func TestVerifyVersionB2(t *testing.T) {
+	webBundle, err := os.ReadFile("./testfile.wbn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	webBundleHash, err := ComputeWebBundleSha512(bytes.NewReader(webBundle), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldPub, oldPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPub, newPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	webBundleId, err := webbundleid.GetWebBundleId(oldPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sign returns the web bundle signed with a version 2 integrity block declaring webBundleId.
+	sign := func(privateKeys ...ed25519.PrivateKey) []byte {
+		ibs := IntegrityBlockSigner{
+			WebBundleHash:  webBundleHash,
+			IntegrityBlock: generateEmptyIntegrityBlock(),
+		}
+		if err := ibs.IntegrityBlock.SetWebBundleId(webBundleId); err != nil {
+			t.Fatal(err)
+		}
+		for _, priv := range privateKeys {
+			pub := priv.Public().(ed25519.PublicKey)
+			ibs.SigningStrategy = NewParsedEd25519KeySigningStrategy(priv)
+			if err := ibs.SignAndAddNewSignature(pub, mustGenerateSignatureAttributes(t, pub)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		integrityBlockBytes, err := ibs.IntegrityBlock.CborBytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cbor.Deterministic(integrityBlockBytes); err != nil {
+			t.Fatal(err)
+		}
+
+		ib, offset, err := ParseIntegrityBlock(bytes.NewReader(integrityBlockBytes))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if offset != int64(len(integrityBlockBytes)) || !reflect.DeepEqual(ib, ibs.IntegrityBlock) {
+			t.Errorf("ParseIntegrityBlock: got %v (%d bytes), want %v (%d bytes)", ib, offset, ibs.IntegrityBlock, len(integrityBlockBytes))
+		}
+		return append(integrityBlockBytes, webBundle...)
+	}
+
+	// Signed with the key of the Web Bundle ID and co-signed with the new key.
+	result, err := Verify(bytes.NewReader(sign(oldPriv, newPriv)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.WebBundleId != webBundleId {
+		t.Errorf("WebBundleId: got %q, want %q", result.WebBundleId, webBundleId)
+	}
+	if want := []crypto.PublicKey{newPub, oldPub}; !reflect.DeepEqual(result.PublicKeys, want) {
+		t.Errorf("PublicKeys: got %v, want %v", result.PublicKeys, want)
+	}
+
+	// After the key rotation, only the new key signs.
+	rotated := sign(newPriv)
+	if _, err := Verify(bytes.NewReader(rotated)); err == nil {
+		t.Error("Verify of a bundle not signed with the key of its Web Bundle ID unexpectedly succeeded")
+	}
+	result, err = VerifyWithTrustedPublicKey(bytes.NewReader(rotated), newPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.WebBundleId != webBundleId {
+		t.Errorf("WebBundleId: got %q, want %q", result.WebBundleId, webBundleId)
+	}
+	if _, err := VerifyWithTrustedPublicKey(bytes.NewReader(rotated), oldPub); err == nil {
+		t.Error("VerifyWithTrustedPublicKey with a key that didn't sign unexpectedly succeeded")
+	}
+}
+
+func TestSetWebBundleId(t *testing.T) {
+	ib := generateEmptyIntegrityBlock()
+	if err := ib.SetWebBundleId("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if !ib.IsVersionB2() || ib.WebBundleId() != "foo" {
+		t.Errorf("got version %q and Web Bundle ID %q, want version 2 and \"foo\"", ib.Version, ib.WebBundleId())
+	}
+
+	ib.addNewSignatureToIntegrityBlock(SignatureAttributesMap{}, []byte("signature"))
+	if err := ib.SetWebBundleId("foo"); err != nil {
+		t.Errorf("SetWebBundleId with the same Web Bundle ID: %v", err)
+	}
+	if err := ib.SetWebBundleId("bar"); err == nil {
+		t.Error("SetWebBundleId of a signed integrity block unexpectedly succeeded")
+	}
+
+	ib = generateEmptyIntegrityBlock()
+	ib.addNewSignatureToIntegrityBlock(SignatureAttributesMap{}, []byte("signature"))
+	if err := ib.SetWebBundleId("foo"); err == nil {
+		t.Error("SetWebBundleId of a signed version b1 integrity block unexpectedly succeeded")
+	}
+}
+
+func TestVerifyEcdsaP256(t *testing.T) {
+	webBundle, err := os.ReadFile("./testfile.wbn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	webBundleHash, err := ComputeWebBundleSha512(bytes.NewReader(webBundle), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecdsaPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaStrategy, err := NewParsedEcdsaP256KeySigningStrategy(ecdsaPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed25519Pub, ed25519Priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ibs := IntegrityBlockSigner{
+		WebBundleHash:  webBundleHash,
+		IntegrityBlock: generateEmptyIntegrityBlock(),
+	}
+	for _, strategy := range []ISigningStrategy{NewParsedEd25519KeySigningStrategy(ed25519Priv), ecdsaStrategy} {
+		ibs.SigningStrategy = strategy
+		pub, err := strategy.GetPublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ibs.SignAndAddNewSignature(pub, mustGenerateSignatureAttributes(t, pub)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(ibs.IntegrityBlock.SignatureStack[0].SignatureAttributes[EcdsaP256SHA256PublicKeyAttributeName]); got != 33 {
+		t.Errorf("ECDSA P-256 public key attribute: got %d bytes, want 33", got)
+	}
+
+	integrityBlockBytes, err := ibs.IntegrityBlock.CborBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := append(integrityBlockBytes, webBundle...)
+
+	result, err := Verify(bytes.NewReader(signed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []crypto.PublicKey{&ecdsaPriv.PublicKey, ed25519Pub}; !reflect.DeepEqual(result.PublicKeys, want) {
+		t.Errorf("PublicKeys: got %v, want %v", result.PublicKeys, want)
+	}
+	want, err := webbundleid.GetWebBundleId(&ecdsaPriv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.WebBundleId != want {
+		t.Errorf("WebBundleId: got %q, want %q", result.WebBundleId, want)
+	}
+	if !ibs.IntegrityBlock.HasSignatureWithPublicKey(&ecdsaPriv.PublicKey) {
+		t.Error("HasSignatureWithPublicKey: got false, want true")
+	}
+
+	signed[len(signed)-10] ^= 1
+	if _, err := Verify(bytes.NewReader(signed)); err == nil {
+		t.Error("Verify of a modified web bundle unexpectedly succeeded")
+	}
+}
+
+func TestUnsupportedPublicKeys(t *testing.T) {
+	p384Priv, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewParsedEcdsaP256KeySigningStrategy(p384Priv); err == nil {
+		t.Error("NewParsedEcdsaP256KeySigningStrategy with a P-384 key unexpectedly succeeded")
+	}
+	if _, err := GenerateSignatureAttributesWithPublicKey(&p384Priv.PublicKey); err == nil {
+		t.Error("GenerateSignatureAttributesWithPublicKey with a P-384 key unexpectedly succeeded")
+	}
+
+	ed25519Pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	is := &IntegritySignature{SignatureAttributes: SignatureAttributesMap{
+		Ed25519publicKeyAttributeName:         []byte(ed25519Pub),
+		EcdsaP256SHA256PublicKeyAttributeName: make([]byte, 33),
+	}}
+	if _, err := is.PublicKey(); err == nil {
+		t.Error("PublicKey of a signature with two public keys unexpectedly succeeded")
+	}
+	is = &IntegritySignature{SignatureAttributes: SignatureAttributesMap{
+		EcdsaP256SHA256PublicKeyAttributeName: make([]byte, 33),
+	}}
+	if _, err := is.PublicKey(); err == nil {
+		t.Error("PublicKey of an invalid ECDSA P-256 public key unexpectedly succeeded")
+	}
+}
+
+// signerKeyEnv holds the hex-encoded Ed25519 seed that TestExternalCommandHelper signs with,
+// or "fail" to make it fail.
+const signerKeyEnv = "INTEGRITYBLOCK_TEST_SIGNER_KEY"
+
+// externalTestArgs make the test binary run TestExternalCommandHelper as the external
+// signing command.
+var externalTestArgs = []string{"-test.run=^TestExternalCommandHelper$", "--"}
+
+// TestExternalCommandHelper isn't a real test: it is run as the external signing command by
+// TestExternalCommandSigningStrategy.
+func TestExternalCommandHelper(t *testing.T) {
+	if os.Getenv(signerKeyEnv) == "" {
+		return
+	}
+	seed, err := hex.DecodeString(os.Getenv(signerKeyEnv))
+	if err != nil {
+		os.Exit(1)
+	}
+	priv := ed25519.NewKeyFromSeed(seed)
+	switch os.Args[len(os.Args)-1] {
+	case ExternalCommandPublicKeyArg:
+		der, err := x509.MarshalPKIXPublicKey(priv.Public())
+		if err != nil {
+			os.Exit(1)
+		}
+		pem.Encode(os.Stdout, &pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	case ExternalCommandSignArg:
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			os.Exit(1)
+		}
+		os.Stdout.Write(ed25519.Sign(priv, data))
+	default:
+		os.Exit(2)
+	}
+	os.Exit(0)
+}
+
+func TestExternalCommandSigningStrategy(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(signerKeyEnv, hex.EncodeToString(priv.Seed()))
+
+	// The program is in a directory with a space in its name, which must not split it.
+	program := filepath.Join(t.TempDir(), "signing command", "signer")
+	if err := os.Mkdir(filepath.Dir(program), 0755); err != nil {
+		t.Fatal(err)
+	}
+	testBinary, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(testBinary, program); err != nil {
+		t.Skipf("cannot link the test binary: %v", err)
+	}
+
+	bss, err := NewExternalCommandSigningStrategy(program, externalTestArgs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotPub, err := bss.GetPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pub.Equal(gotPub) {
+		t.Errorf("GetPublicKey: got %v, want %v", gotPub, pub)
+	}
+
+	webBundle, err := os.ReadFile("./testfile.wbn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	webBundleHash, err := ComputeWebBundleSha512(bytes.NewReader(webBundle), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ibs := IntegrityBlockSigner{
+		SigningStrategy: bss,
+		WebBundleHash:   webBundleHash,
+		IntegrityBlock:  generateEmptyIntegrityBlock(),
+	}
+	if err := ibs.SignAndAddNewSignature(gotPub, mustGenerateSignatureAttributes(t, gotPub)); err != nil {
+		t.Fatal(err)
+	}
+	integrityBlockBytes, err := ibs.IntegrityBlock.CborBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(bytes.NewReader(append(integrityBlockBytes, webBundle...))); err != nil {
+		t.Error(err)
+	}
+
+	// A command signing with another key than the public key it printed.
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(signerKeyEnv, hex.EncodeToString(otherPriv.Seed()))
+	ibs.IntegrityBlock = generateEmptyIntegrityBlock()
+	if err := ibs.SignAndAddNewSignature(gotPub, mustGenerateSignatureAttributes(t, gotPub)); err == nil {
+		t.Error("SignAndAddNewSignature with a mismatching signing command unexpectedly succeeded")
+	}
+}
+
+func TestExternalCommandSigningStrategyErrors(t *testing.T) {
+	if _, err := NewExternalCommandSigningStrategy(""); err == nil {
+		t.Error("NewExternalCommandSigningStrategy with an empty command unexpectedly succeeded")
+	}
+
+	t.Setenv(signerKeyEnv, "fail")
+	bss, err := NewExternalCommandSigningStrategy(os.Args[0], externalTestArgs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bss.GetPublicKey(); err == nil {
+		t.Error("GetPublicKey with a failing command unexpectedly succeeded")
+	}
+	if _, err := bss.Sign([]byte("data")); err == nil {
+		t.Error("Sign with a failing command unexpectedly succeeded")
+	}
+}
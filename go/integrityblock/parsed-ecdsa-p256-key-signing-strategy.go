@@ -0,0 +1,35 @@
+package integrityblock
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+)
+
+// ParsedEcdsaP256KeySigningStrategy implementing `ISigningStrategy` signs a web bundle with
+// a parsed ECDSA P-256 private key, using SHA-256 as the hash function.
+type ParsedEcdsaP256KeySigningStrategy struct {
+	ecdsaPrivKey *ecdsa.PrivateKey
+}
+
+func NewParsedEcdsaP256KeySigningStrategy(ecdsaPrivKey *ecdsa.PrivateKey) (*ParsedEcdsaP256KeySigningStrategy, error) {
+	if ecdsaPrivKey.Curve != elliptic.P256() {
+		return nil, errors.New("integrityblock: ECDSA private key must be on the P-256 curve.")
+	}
+	return &ParsedEcdsaP256KeySigningStrategy{
+		ecdsaPrivKey: ecdsaPrivKey,
+	}, nil
+}
+
+// Sign returns the ASN.1 DER-encoded ECDSA signature of the SHA-256 hash of data.
+func (bss ParsedEcdsaP256KeySigningStrategy) Sign(data []byte) ([]byte, error) {
+	hash := sha256.Sum256(data)
+	return ecdsa.SignASN1(rand.Reader, bss.ecdsaPrivKey, hash[:])
+}
+
+func (bss ParsedEcdsaP256KeySigningStrategy) GetPublicKey() (crypto.PublicKey, error) {
+	return &bss.ecdsaPrivKey.PublicKey, nil
+}
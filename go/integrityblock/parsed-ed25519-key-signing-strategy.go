@@ -1,6 +1,7 @@
 package integrityblock
 
 import (
+	"crypto"
 	"crypto/ed25519"
 )
 
@@ -20,6 +21,6 @@ func (bss ParsedEd25519KeySigningStrategy) Sign(data []byte) ([]byte, error) {
 	return ed25519.Sign(bss.ed25519privKey, data), nil
 }
 
-func (bss ParsedEd25519KeySigningStrategy) GetPublicKey() (ed25519.PublicKey, error) {
+func (bss ParsedEd25519KeySigningStrategy) GetPublicKey() (crypto.PublicKey, error) {
 	return bss.ed25519privKey.Public().(ed25519.PublicKey), nil
 }
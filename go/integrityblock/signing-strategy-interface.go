@@ -1,12 +1,14 @@
 package integrityblock
 
 import (
-	"crypto/ed25519"
+	"crypto"
 )
 
+// ISigningStrategy signs web bundles with either an Ed25519 or an ECDSA P-256 key.
+// GetPublicKey returns an ed25519.PublicKey or an *ecdsa.PublicKey accordingly.
 type ISigningStrategy interface {
 	Sign(data []byte) ([]byte, error)
-	GetPublicKey() (ed25519.PublicKey, error)
+	GetPublicKey() (crypto.PublicKey, error)
 
 	// TODO(sonkkeli): Implement once we have security approval.
 	// IsDevSigning() bool
@@ -1,19 +1,41 @@
 package webbundleid
 
 import (
+	"crypto"
+	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
 	"encoding/base32"
+	"errors"
+	"fmt"
 	"strings"
 )
 
-var webBundleIdSuffix = []byte{0x00, 0x01, 0x02}
+var (
+	ed25519WebBundleIdSuffix   = []byte{0x00, 0x01, 0x02}
+	ecdsaP256WebBundleIdSuffix = []byte{0x00, 0x02, 0x02}
+)
 
-// GetWebBundleId returns a base32-encoded (without padding) ed25519 public key
-// combined with a 3-byte long suffix and transformed to lowercase. More information:
+// GetWebBundleId returns a base32-encoded (without padding) public key combined with
+// a 3-byte long suffix identifying the key type, transformed to lowercase. The public key
+// is either an ed25519 public key, or an ECDSA P-256 public key in its compressed 33-byte
+// form. More information:
 // https://github.com/WICG/isolated-web-apps/blob/main/Scheme.md#signed-web-bundle-ids
-func GetWebBundleId(ed25519publicKey ed25519.PublicKey) string {
-	keyWithSuffix := append([]byte(ed25519publicKey), webBundleIdSuffix...)
+func GetWebBundleId(publicKey crypto.PublicKey) (string, error) {
+	var keyWithSuffix []byte
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		keyWithSuffix = append([]byte(key), ed25519WebBundleIdSuffix...)
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return "", errors.New("webbundleid: ECDSA public key must be on the P-256 curve.")
+		}
+		keyWithSuffix = append(elliptic.MarshalCompressed(key.Curve, key.X, key.Y), ecdsaP256WebBundleIdSuffix...)
+	default:
+		return "", fmt.Errorf("webbundleid: Unsupported public key type: %T", publicKey)
+	}
 
-	// StdEncoding is the standard base32 encoding, as defined in RFC 4648.
-	return strings.ToLower(base32.StdEncoding.EncodeToString(keyWithSuffix))
+	// StdEncoding is the standard base32 encoding, as defined in RFC 4648. Ed25519 keys with
+	// the suffix need no padding, but ECDSA P-256 keys do, and the padding is left out.
+	return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(keyWithSuffix)), nil
 }
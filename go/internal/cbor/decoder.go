@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math"
 	"unicode/utf8"
 )
 
@@ -96,6 +97,9 @@ func (d *Decoder) decodeBytesOfType(expected Type) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if n > math.MaxInt64 {
+		return nil, fmt.Errorf("cbor: length %d is too large", n)
+	}
 	bs := new(bytes.Buffer)
 	if _, err := io.CopyN(bs, d.r, int64(n)); err != nil {
 		return nil, err
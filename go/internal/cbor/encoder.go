@@ -112,6 +112,13 @@ func (e *Encoder) EncodeByteString(bs []byte) error {
 	return e.encodeBytes(TypeBytes, bs)
 }
 
+// EncodeByteStringHeader writes only the initial bytes of a byte string of
+// length n. The caller is responsible for writing exactly n bytes of content
+// after it.
+func (e *Encoder) EncodeByteStringHeader(n uint64) error {
+	return e.encodeTypedUint(TypeBytes, n)
+}
+
 func (e *Encoder) EncodeTextString(s string) error {
 	// Major type 3:  a text string, specifically a string of Unicode
 	//   characters that is encoded as UTF-8 [RFC3629].  The format of this
@@ -0,0 +1,17 @@
+// Package mimetype provides helpers for the MIME types of response bodies.
+package mimetype
+
+import (
+	"mime"
+	"strings"
+)
+
+// IsText reports whether a Content-Type header value denotes a textual body
+// that can be shown to humans as is. Unparsable values are not text.
+func IsText(mimeType string) bool {
+	m, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(m, "text/") || m == "application/javascript"
+}
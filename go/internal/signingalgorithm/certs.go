@@ -121,10 +121,12 @@ func ParsePublicKey(text []byte) (crypto.PublicKey, error) {
 	return nil, errors.New("signingalgorithm: could not find public key.")
 }
 
-// parsePublicKeyBlock parses any allowed type public key. Currently only allows parsing ed25519 type public keys.
+// parsePublicKeyBlock parses any allowed type public key. Currently only allows parsing ed25519 and ecdsa type public keys.
 func parsePublicKeyBlock(derKey []byte) (crypto.PublicKey, error) {
 	if keyInterface, err := x509.ParsePKIXPublicKey(derKey); err == nil {
 		switch typedKey := keyInterface.(type) {
+		case *ecdsa.PublicKey:
+			return typedKey, nil
 		case ed25519.PublicKey:
 			return typedKey, nil
 		default:
@@ -0,0 +1,176 @@
+// Package textdiff computes line-based diffs of texts.
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContextLines is the number of unchanged lines shown around each
+	// change in a unified diff.
+	diffContextLines = 3
+	// maxDiffEdits bounds the work of diffLines. Texts that need more edits
+	// are shown as a whole removal followed by a whole addition.
+	maxDiffEdits = 2000
+)
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+	// aLine and bLine are the numbers of lines of a and b before this op.
+	aLine, bLine int
+}
+
+// splitLines splits s into lines, keeping the line terminators.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns a shortest edit script turning a into b, using Myers'
+// algorithm.
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	// v[k+offset] is the furthest x reached on diagonal k = x-y.
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	// trace[d] holds v[-d..d] before step d, for backtracking.
+	var trace [][]int
+	found := false
+	for d := 0; d <= n+m && d <= maxDiffEdits && !found; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		var ops []diffOp
+		for i, line := range a {
+			ops = append(ops, diffOp{'-', line, i, 0})
+		}
+		for i, line := range b {
+			ops = append(ops, diffOp{'+', line, n, i})
+		}
+		return ops
+	}
+
+	var rev []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		vd := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && vd[k-1+d] < vd[k+1+d]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := 0
+		if d > 0 {
+			prevX = vd[prevK+d]
+		}
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			rev = append(rev, diffOp{' ', a[x], x, y})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				rev = append(rev, diffOp{'+', b[y], x, y})
+			} else {
+				x--
+				rev = append(rev, diffOp{'-', a[x], x, y})
+			}
+		}
+	}
+	ops := make([]diffOp, len(rev))
+	for i, op := range rev {
+		ops[len(rev)-1-i] = op
+	}
+	return ops
+}
+
+// Unified returns the hunks of a unified diff between the texts a and b,
+// without the file header lines. It returns "" if a and b are equal.
+func Unified(a, b string) string {
+	if a == b {
+		return ""
+	}
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	var sb strings.Builder
+	for i := 0; i < len(ops); {
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+		// Extend the hunk while the next change is close enough for the
+		// context lines to overlap.
+		start := i - diffContextLines
+		if start < 0 {
+			start = 0
+		}
+		end := i + 1
+		for j := end; j < len(ops) && j < end+2*diffContextLines; j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			}
+		}
+		end += diffContextLines
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		aStart, bStart := ops[start].aLine, ops[start].bLine
+		aCount, bCount := 0, 0
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+		// Line numbers are 1-based, except that an empty range refers to
+		// the line before it.
+		if aCount > 0 {
+			aStart++
+		}
+		if bCount > 0 {
+			bStart++
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+		for _, op := range ops[start:end] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return sb.String()
+}
@@ -0,0 +1,56 @@
+package textdiff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	cases := []struct {
+		name string
+		old  string
+		new  string
+		want string
+	}{
+		{
+			name: "Equal",
+			old:  "a\n",
+			new:  "a\n",
+			want: "",
+		},
+		{
+			name: "Change",
+			old:  "a\nb\nc\nd\ne\nf\ng\nh\n",
+			new:  "a\nb\nc\nd\nE\nf\ng\nh\n",
+			want: "@@ -2,7 +2,7 @@\n b\n c\n d\n-e\n+E\n f\n g\n h\n",
+		},
+		{
+			name: "Append",
+			old:  "a\nb\n",
+			new:  "a\nb\nc\n",
+			want: "@@ -1,2 +1,3 @@\n a\n b\n+c\n",
+		},
+		{
+			name: "FromEmpty",
+			old:  "",
+			new:  "a\n",
+			want: "@@ -0,0 +1,1 @@\n+a\n",
+		},
+		{
+			name: "NoNewlineAtEnd",
+			old:  "a\nb",
+			new:  "a\nc",
+			want: "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n",
+		},
+		{
+			name: "TwoHunks",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			new:  "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			want: "@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n@@ -9,4 +10,3 @@\n 9\n 10\n 11\n-12\n",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := Unified(c.old, c.new); got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}